@@ -14,5 +14,5 @@ sleep 120 # allow the server enough time to start
 
 # run the rover canary tests
 cd /home/rover-canary/rover-canary
-sudo go run main.go
+sudo go run . --config canary.json
 
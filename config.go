@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// environment variables that override values loaded from the config file
+const (
+	envAddress  = "ROVER_CANARY_ADDRESS"
+	envAPIKeyID = "ROVER_CANARY_API_KEY_ID"
+	envAPIKey   = "ROVER_CANARY_API_KEY"
+	envPartID   = "ROVER_CANARY_PART_ID"
+	envWebhook  = "ROVER_CANARY_WEBHOOK"
+	envProfile  = "ROVER_CANARY_PROFILE"
+)
+
+// canaryConfig holds the machine credentials and endpoints used by a canary run.
+type canaryConfig struct {
+	Address  string `json:"address" yaml:"address"`
+	APIKeyID string `json:"api_key_id" yaml:"api_key_id"`
+	APIKey   string `json:"api_key" yaml:"api_key"`
+	PartID   string `json:"part_id" yaml:"part_id"`
+	Webhook  string `json:"webhook" yaml:"webhook"`
+
+	// Profile names the hardware profile of the rover under test, CustomProfile is used when it is "custom".
+	Profile       string          `json:"hardware_profile" yaml:"hardware_profile"`
+	CustomProfile hardwareProfile `json:"custom_profile" yaml:"custom_profile"`
+
+	// Plan is the path to a test plan file, the built-in plan is used when empty.
+	Plan       string         `json:"plan" yaml:"plan"`
+	Components componentNames `json:"components" yaml:"components"`
+
+	// MinBatteryVoltage is the lowest pack voltage motion suites start at, defaulting to the bottom of the
+	// hardware profile's nominal range.
+	MinBatteryVoltage float64 `json:"min_battery_voltage" yaml:"min_battery_voltage"`
+}
+
+// componentNames binds each logical role in the canary to a resource name on the machine.
+// A role left empty is not configured and the suites that need it are skipped.
+type componentNames struct {
+	WheeledBase    string `json:"wheeled_base" yaml:"wheeled_base"`
+	SensorBase     string `json:"sensor_base" yaml:"sensor_base"`
+	LeftMotor      string `json:"left_motor" yaml:"left_motor"`
+	RightMotor     string `json:"right_motor" yaml:"right_motor"`
+	LeftEncoder    string `json:"left_encoder" yaml:"left_encoder"`
+	RightEncoder   string `json:"right_encoder" yaml:"right_encoder"`
+	Odometry       string `json:"odometry" yaml:"odometry"`
+	PowerSensor    string `json:"power_sensor" yaml:"power_sensor"`
+	MovementSensor string `json:"movement_sensor" yaml:"movement_sensor"`
+}
+
+// loadConfig reads the config file at path (JSON or YAML, chosen by extension), applies any
+// environment variable overrides and validates the result. An empty path loads only from the environment.
+func loadConfig(path string) (canaryConfig, error) {
+	cfg, err := readConfig(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// readConfig reads the config file at path and applies any environment variable overrides without validating.
+func readConfig(path string) (canaryConfig, error) {
+	var cfg canaryConfig
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("error reading config file %q, err = %w", path, err)
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(raw, &cfg)
+		default:
+			err = json.Unmarshal(raw, &cfg)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("error parsing config file %q, err = %w", path, err)
+		}
+	}
+
+	cfg.applyEnv()
+	return cfg, nil
+}
+
+// applyEnv overrides config fields with any non-empty environment variables.
+func (cfg *canaryConfig) applyEnv() {
+	overrides := map[string]*string{
+		envAddress:  &cfg.Address,
+		envAPIKeyID: &cfg.APIKeyID,
+		envAPIKey:   &cfg.APIKey,
+		envPartID:   &cfg.PartID,
+		envWebhook:  &cfg.Webhook,
+		envProfile:  &cfg.Profile,
+	}
+	for env, field := range overrides {
+		if val, ok := os.LookupEnv(env); ok && val != "" {
+			*field = val
+		}
+	}
+}
+
+// validate returns an error listing every required field that is missing or still a placeholder.
+func (cfg canaryConfig) validate() error {
+	var errs []error
+	required := []struct {
+		name string
+		val  string
+	}{
+		{"address", cfg.Address},
+		{"api_key_id", cfg.APIKeyID},
+		{"api_key", cfg.APIKey},
+		{"part_id", cfg.PartID},
+		{"webhook", cfg.Webhook},
+	}
+	for _, field := range required {
+		val := strings.TrimSpace(field.val)
+		if val == "" {
+			errs = append(errs, fmt.Errorf("config field %q is required", field.name))
+			continue
+		}
+		if strings.HasPrefix(val, "<") && strings.HasSuffix(val, ">") {
+			errs = append(errs, fmt.Errorf("config field %q is still the placeholder %v", field.name, val))
+		}
+	}
+	if cfg.Webhook != "" && !strings.HasPrefix(cfg.Webhook, "http://") && !strings.HasPrefix(cfg.Webhook, "https://") {
+		errs = append(errs, fmt.Errorf("config field %q must be an http(s) url", "webhook"))
+	}
+	if cfg.MinBatteryVoltage < 0 {
+		errs = append(errs, fmt.Errorf("config field %q cannot be negative", "min_battery_voltage"))
+	}
+	if _, err := cfg.hardwareProfile(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
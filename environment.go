@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+)
+
+const unknown = "unknown"
+
+// runEnvironment records what a run tested and what it was built from, so a regression can be tied to a
+// server upgrade, a new canary build, a changed machine or a different host.
+type runEnvironment struct {
+	// as reported by the robot
+	ServerPlatform string   `json:"server_platform"`
+	ServerVersion  string   `json:"server_version"`
+	APIVersion     string   `json:"api_version"`
+	Resources      []string `json:"resources"`
+
+	// the canary's own build
+	CanaryVersion  string `json:"canary_version"`
+	CanaryRevision string `json:"canary_revision"`
+	CanaryModified bool   `json:"canary_modified"`
+	ClientRDK      string `json:"client_rdk_version"`
+	GoVersion      string `json:"go_version"`
+
+	Host    string `json:"host"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Profile string `json:"hardware_profile"`
+}
+
+// envLabel is one environment value, named the same way in reports and upload tags.
+type envLabel struct {
+	name  string
+	value string
+}
+
+// collectEnvironment reads the robot's version and resources and the canary's build info. Anything that cannot
+// be read is logged and recorded as unknown.
+func (r *Runner) collectEnvironment(ctx context.Context) runEnvironment {
+	env := runEnvironment{
+		ServerPlatform: unknown,
+		ServerVersion:  unknown,
+		APIVersion:     unknown,
+		CanaryVersion:  unknown,
+		CanaryRevision: unknown,
+		ClientRDK:      unknown,
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		Profile:        r.profile.Name,
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		env.Host = host
+	} else {
+		r.logger.Errorf("error reading hostname, err = %v", err)
+	}
+
+	if r.machine != nil {
+		version, err := r.machine.Version(ctx)
+		if err != nil {
+			r.logger.Errorf("error reading robot version, err = %v", err)
+		} else {
+			env.ServerPlatform, env.ServerVersion, env.APIVersion = version.Platform, version.Version, version.APIVersion
+		}
+		for _, name := range r.machine.ResourceNames() {
+			env.Resources = append(env.Resources, name.String())
+		}
+		sort.Strings(env.Resources)
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		r.logger.Error("error reading canary build info")
+		return env
+	}
+	env.CanaryVersion = info.Main.Version
+	env.GoVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			env.CanaryRevision = setting.Value
+		case "vcs.modified":
+			env.CanaryModified = setting.Value == "true"
+		}
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "go.viam.com/rdk" {
+			env.ClientRDK = dep.Version
+		}
+	}
+	return env
+}
+
+// labels returns the environment values reports and uploads are labelled with, resources excluded.
+func (e runEnvironment) labels() []envLabel {
+	revision := e.CanaryRevision
+	if e.CanaryModified {
+		revision += "-modified"
+	}
+	return []envLabel{
+		{"hardware_profile", e.Profile},
+		{"server_platform", e.ServerPlatform},
+		{"server_version", e.ServerVersion},
+		{"api_version", e.APIVersion},
+		{"canary_version", e.CanaryVersion},
+		{"canary_revision", revision},
+		{"client_rdk_version", e.ClientRDK},
+		{"go_version", e.GoVersion},
+		{"host", e.Host},
+		{"os", e.OS},
+		{"arch", e.Arch},
+	}
+}
+
+// tags returns the labels as name:value data upload tags.
+func (e runEnvironment) tags() []string {
+	var tags []string
+	for _, l := range e.labels() {
+		tags = append(tags, fmt.Sprintf("%v:%v", l.name, l.value))
+	}
+	return tags
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	rdkutils "go.viam.com/rdk/utils"
+
+	"rovercanary/samples"
+)
+
+// estimators a plan step can name to decide its speed
+const (
+	estimatorMargin      = "margin"
+	estimatorSlope       = "slope"
+	estimatorTrimmedMean = "trimmed_mean"
+	estimatorMedian      = "median"
+)
+
+var estimators = []string{estimatorMargin, estimatorSlope, estimatorTrimmedMean, estimatorMedian}
+
+// default estimator of each operation that estimates a speed. Velocity steps are sampled at steady state, so
+// every sample counts; rpm is derived from jittery ticker intervals, so it is fit from the motor position
+// instead; motions sample their ramps too, which the median ignores.
+var defaultEstimator = map[string]string{
+	opSetVelocity:         estimatorTrimmedMean,
+	opConsecutiveVelocity: estimatorTrimmedMean,
+	opMoveStraight:        estimatorMedian,
+	opSpin:                estimatorMedian,
+	opGoFor:               estimatorMedian,
+	opGoTo:                estimatorMedian,
+	opSetRPM:              estimatorSlope,
+	opConsecutiveRPM:      estimatorSlope,
+}
+
+// margins used by the margin estimator, as a fraction of the goal or an absolute value when the goal is zero
+const (
+	estimateMargin       = 0.5
+	defaultLinearMargin  = 50.0 // mm/sec
+	defaultAngularMargin = 15.0 // deg/sec
+	defaultRPMMargin     = 0.0
+)
+
+// trimFraction is the fraction of samples the trimmed mean drops from each end.
+const trimFraction = 0.2
+
+// speedSample is one sample of a motion: seconds since the run started, how far it had moved and its
+// instantaneous speed. position is in units whose rate of change per second is the speed.
+type speedSample struct {
+	sec      float64
+	position float64
+	speed    float64
+}
+
+// estimator turns the samples of a motion into the single speed a test is judged on. Estimators are shared by
+// live runs and replay.
+type estimator interface {
+	add(s speedSample)
+	estimate() float64
+}
+
+// newEstimator returns the named estimator for a speed goal, defaultMargin is the margin estimator's margin
+// when the goal is zero. An empty name is the median.
+func newEstimator(name string, goal, defaultMargin float64) (estimator, error) {
+	switch name {
+	case estimatorMargin:
+		return newMarginEstimator(goal, defaultMargin), nil
+	case estimatorSlope:
+		return &slopeEstimator{}, nil
+	case estimatorTrimmedMean:
+		return &trimmedMeanEstimator{}, nil
+	case "", estimatorMedian:
+		return &medianEstimator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown estimator %q, expected one of %v", name, estimators)
+	}
+}
+
+// marginEstimator reports the latest sample within the margin of the goal, or the fastest sample in the goal's
+// direction when none was. It is the canary's original estimator and depends on a single sample.
+type marginEstimator struct {
+	goal   float64
+	margin float64
+	best   float64
+	max    float64
+}
+
+func newMarginEstimator(goal, defaultMargin float64) *marginEstimator {
+	margin := defaultMargin
+	if goal != 0 {
+		margin = math.Abs(goal) * estimateMargin
+	}
+	return &marginEstimator{goal: goal, margin: margin}
+}
+
+func (e *marginEstimator) add(s speedSample) {
+	// check if speed is within the error margin of the goal
+	if rdkutils.Float64AlmostEqual(e.goal, s.speed, e.margin) {
+		e.best = s.speed
+	}
+	// check if speed is greater than the current max in the direction of the goal
+	if (e.goal < 0 && s.speed < e.max) || (e.goal > 0 && s.speed > e.max) {
+		e.max = s.speed
+	}
+}
+
+func (e *marginEstimator) estimate() float64 {
+	if e.best == 0 {
+		return e.max
+	}
+	return e.best
+}
+
+// slopeEstimator fits position over time by least squares and reports the slope, so no single sample or
+// interval decides the speed.
+type slopeEstimator struct {
+	n                        float64
+	sumT, sumX, sumTT, sumTX float64
+}
+
+func (e *slopeEstimator) add(s speedSample) {
+	e.n++
+	e.sumT += s.sec
+	e.sumX += s.position
+	e.sumTT += s.sec * s.sec
+	e.sumTX += s.sec * s.position
+}
+
+func (e *slopeEstimator) estimate() float64 {
+	denom := e.n*e.sumTT - e.sumT*e.sumT
+	if e.n < 2 || denom == 0 {
+		return 0
+	}
+	return (e.n*e.sumTX - e.sumT*e.sumX) / denom
+}
+
+// trimmedMeanEstimator reports the mean speed after dropping trimFraction of the samples from each end.
+type trimmedMeanEstimator struct {
+	speeds []float64
+}
+
+func (e *trimmedMeanEstimator) add(s speedSample) {
+	e.speeds = append(e.speeds, s.speed)
+}
+
+func (e *trimmedMeanEstimator) estimate() float64 {
+	if len(e.speeds) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), e.speeds...)
+	sort.Float64s(sorted)
+	trim := int(float64(len(sorted)) * trimFraction)
+	kept := sorted[trim : len(sorted)-trim]
+	sum := 0.
+	for _, v := range kept {
+		sum += v
+	}
+	return sum / float64(len(kept))
+}
+
+// medianEstimator reports the median speed.
+type medianEstimator struct {
+	speeds []float64
+}
+
+func (e *medianEstimator) add(s speedSample) {
+	e.speeds = append(e.speeds, s.speed)
+}
+
+func (e *medianEstimator) estimate() float64 {
+	if len(e.speeds) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), e.speeds...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// speedEstimates feeds measured records to estimators of the linear velocity (mm/sec), angular velocity
+// (deg/sec) and rpm of a motion, tracking how far it moved along the way for the slope estimator.
+type speedEstimates struct {
+	linear, angular, rpm estimator
+	prev                 *samples.Record
+	// distance travelled in mm, signed by the direction of travel
+	distance float64
+	heading  headingTracker
+}
+
+// newSpeedEstimates returns the named estimators for the given goals.
+func newSpeedEstimates(name string, linear, angular, rpm float64) (*speedEstimates, error) {
+	linEst, err := newEstimator(name, linear, defaultLinearMargin)
+	if err != nil {
+		return nil, err
+	}
+	angEst, _ := newEstimator(name, angular, defaultAngularMargin)
+	rpmEst, _ := newEstimator(name, rpm, defaultRPMMargin)
+	return &speedEstimates{linear: linEst, angular: angEst, rpm: rpmEst}, nil
+}
+
+// add adds a measured record. Records of base motions and motor motions are not mixed.
+func (e *speedEstimates) add(rec samples.Record) {
+	if e.prev != nil {
+		dx, dy := rec.X-e.prev.X, rec.Y-e.prev.Y
+		if rec.LinearVelocity != 0 {
+			e.distance += math.Hypot(dx, dy) * 1000 * sign(rec.LinearVelocity)
+		}
+	}
+	e.prev = &rec
+	e.heading.add(rec.Theta)
+
+	sec := float64(rec.TimeMs) / 1000
+	e.linear.add(speedSample{sec: sec, position: e.distance, speed: rec.LinearVelocity})
+	e.angular.add(speedSample{sec: sec, position: e.heading.turned(), speed: rec.AngularVelocity})
+	// revolutions per second times 60 is rpm
+	e.rpm.add(speedSample{sec: sec, position: rec.Position * 60, speed: rec.RPM})
+}
+
+// wrapAngle returns rad wrapped into [-pi, pi).
+func wrapAngle(rad float64) float64 {
+	return rad - 2*math.Pi*math.Floor((rad+math.Pi)/(2*math.Pi))
+}
@@ -28,6 +28,7 @@ func UploadJpeg(
 	apiKey, apiKeyID string,
 	componentType string, // component used for the test
 	testType string,
+	envTags []string, // environment the run was made in
 	logger logging.Logger,
 ) (string, error) {
 	syncClient, conn, err := connectToApp(ctx, apiKey, apiKeyID, logger)
@@ -56,6 +57,7 @@ func UploadJpeg(
 			testType,                                // specific kind of test
 		},
 	}
+	md.Tags = append(md.Tags, envTags...)
 
 	// Send metadata FileUploadRequest.
 	req := &pbDataSync.FileUploadRequest{
@@ -80,10 +82,19 @@ func UploadJpeg(
 }
 
 func connectToApp(ctx context.Context, apiKey, apiKeyID string, logger logging.Logger) (pbDataSync.DataSyncServiceClient, rpc.ClientConn, error) {
-	u, err := url.Parse(appURL)
+	conn, err := dialApp(ctx, apiKey, apiKeyID, logger)
 	if err != nil {
 		return nil, nil, err
 	}
+	return pbDataSync.NewDataSyncServiceClient(conn), conn, nil
+}
+
+// dialApp opens a connection to app authenticated with an api key.
+func dialApp(ctx context.Context, apiKey, apiKeyID string, logger logging.Logger) (rpc.ClientConn, error) {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := rpc.WithEntityCredentials(
 		apiKeyID,
@@ -92,11 +103,7 @@ func connectToApp(ctx context.Context, apiKey, apiKeyID string, logger logging.L
 			Payload: apiKey,
 		})
 
-	conn, err := rpc.DialDirectGRPC(ctx, u.Host, logger.AsZap(), opts)
-	if err != nil {
-		return nil, nil, err
-	}
-	return pbDataSync.NewDataSyncServiceClient(conn), conn, nil
+	return rpc.DialDirectGRPC(ctx, u.Host, logger.AsZap(), opts)
 }
 
 func readNextFileUploadFileChunk(f *bytes.Buffer) (*pbDataSync.FileData, error) {
@@ -0,0 +1,23 @@
+package fileupload
+
+import (
+	"context"
+
+	pbApp "go.viam.com/api/app/v1"
+	"go.viam.com/rdk/logging"
+)
+
+// GetPartConfig fetches the json config app holds for a robot part.
+func GetPartConfig(ctx context.Context, partID, apiKey, apiKeyID string, logger logging.Logger) (string, error) {
+	conn, err := dialApp(ctx, apiKey, apiKeyID, logger)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	res, err := pbApp.NewAppServiceClient(conn).GetRobotPart(ctx, &pbApp.GetRobotPartRequest{Id: partID})
+	if err != nil {
+		return "", err
+	}
+	return res.GetConfigJson(), nil
+}
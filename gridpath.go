@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"time"
+
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// moves of a grid path
+const (
+	gridStraight = "straight"
+	gridSpin     = "spin"
+	gridArc      = "arc"
+	gridRepeat   = "repeat"
+)
+
+// maxGridMoves caps the moves a path expands to, so a nested repeat can not run the base for hours.
+const maxGridMoves = 500
+
+// defaultGridPattern is the pattern the grid test drives when its plan names none.
+const defaultGridPattern = "lawnmower"
+
+// built-in grid patterns. The lawnmower pattern is the canary's original grid route.
+var gridPatterns = map[string]string{
+	"lawnmower": `
+		straight(1500, 100) spin(90, 30) straight(500, 100) spin(90, 30)
+		straight(1500, 100) spin(-90, 30) straight(500, 100) spin(-90, 30)
+		straight(1500, 100) spin(90, 30) straight(500, 100) spin(90, 30)
+		straight(1500, 100)`,
+	"square": `repeat(4) { straight(1000, 100) spin(90, 30) }`,
+	// a figure eight is a counterclockwise circle followed by a clockwise one
+	"figure-eight": `arc(500, 360, 100) arc(500, -360, 100)`,
+	"circle":       `arc(500, 360, 100)`,
+	"out-and-back": `straight(1500, 100) spin(180, 30) straight(1500, 100) spin(180, 30)`,
+}
+
+// gridPlan picks the path the grid test drives: a built-in pattern by name or a path of its own, written as a
+// sequence of moves:
+//
+//	straight(distance, speed)     mm and mm/sec, backwards when distance is negative
+//	spin(angle, speed)            deg and deg/sec, counterclockwise when angle is positive
+//	arc(radius, angle, speed)     mm, deg and mm/sec, turning left when angle is positive
+//	repeat(n) { ... }             the moves in braces n times
+//
+// Speeds may be left out for the grid's defaults. Moves may be separated by whitespace, commas or semicolons
+// and // starts a comment.
+type gridPlan struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Path    string `json:"path" yaml:"path"`
+}
+
+// gridMove is a single move of a grid path after repeats are expanded.
+type gridMove struct {
+	op       string
+	distance float64 // mm, straights
+	radius   float64 // mm, arcs
+	angle    float64 // deg, spins and arcs
+	speed    float64 // mm/sec for straights and arcs, deg/sec for spins
+}
+
+// name is the pattern the plan drives, or "custom" for a path of its own.
+func (g gridPlan) name() string {
+	switch {
+	case g.Path != "":
+		return "custom"
+	case g.Pattern != "":
+		return g.Pattern
+	default:
+		return defaultGridPattern
+	}
+}
+
+// moves parses the plan's path into the moves to drive.
+func (g gridPlan) moves() ([]gridMove, error) {
+	if g.Path != "" {
+		if g.Pattern != "" {
+			return nil, fmt.Errorf("grid sets both a pattern and a path")
+		}
+		return parseGridPath(g.Path)
+	}
+	src, ok := gridPatterns[g.name()]
+	if !ok {
+		names := make([]string, 0, len(gridPatterns))
+		for name := range gridPatterns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown grid pattern %q, expected one of %v", g.Pattern, names)
+	}
+	return parseGridPath(src)
+}
+
+// duration is how long a move is expected to take, including the pause after a spin.
+func (m gridMove) duration() time.Duration {
+	switch m.op {
+	case gridStraight:
+		return secondsToDuration(math.Abs(m.distance) / m.speed)
+	case gridSpin:
+		return secondsToDuration(math.Abs(m.angle)/m.speed) + gridSpinPause
+	case gridArc:
+		return secondsToDuration(m.length() / m.speed)
+	}
+	return 0
+}
+
+// length is the distance an arc drives in mm.
+func (m gridMove) length() float64 {
+	return m.radius * math.Abs(rdkutils.DegToRad(m.angle))
+}
+
+// angularVelocity is the angular velocity in deg/sec that drives an arc at its speed.
+func (m gridMove) angularVelocity() float64 {
+	return rdkutils.RadToDeg(m.speed/m.radius) * sign(m.angle)
+}
+
+// gridTimeout is the deadline for a whole grid path.
+func gridTimeout(moves []gridMove) time.Duration {
+	var expected time.Duration
+	for _, m := range moves {
+		expected += m.duration()
+	}
+	return timeoutFactor*expected + testTimeoutMargin
+}
+
+// parseGridPath parses a grid path into its moves.
+func parseGridPath(src string) ([]gridMove, error) {
+	p := &pathParser{}
+	p.s.Init(strings.NewReader(src))
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanComments | scanner.SkipComments
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		p.err = p.errorf("%v", msg)
+	}
+	p.next()
+	moves, err := p.path(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("grid path has no moves")
+	}
+	return moves, nil
+}
+
+type pathParser struct {
+	s   scanner.Scanner
+	tok rune
+	err error
+}
+
+func (p *pathParser) next() {
+	p.tok = p.s.Scan()
+}
+
+func (p *pathParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("grid path %v: %v", p.s.Position, fmt.Sprintf(format, a...))
+}
+
+// path parses moves until the end of the source, or the closing brace of a repeat block when inBlock.
+func (p *pathParser) path(inBlock bool) ([]gridMove, error) {
+	var moves []gridMove
+	for {
+		if p.err != nil {
+			return nil, p.err
+		}
+		switch p.tok {
+		case scanner.EOF:
+			if inBlock {
+				return nil, p.errorf("repeat block is missing its closing }")
+			}
+			return moves, nil
+		case '}':
+			if !inBlock {
+				return nil, p.errorf("unexpected }")
+			}
+			p.next()
+			return moves, nil
+		case ',', ';':
+			p.next()
+		case scanner.Ident:
+			more, err := p.move()
+			if err != nil {
+				return nil, err
+			}
+			moves = append(moves, more...)
+			if len(moves) > maxGridMoves {
+				return nil, p.errorf("path has more than %d moves", maxGridMoves)
+			}
+		default:
+			return nil, p.errorf("unexpected %q, expected a move", p.s.TokenText())
+		}
+	}
+}
+
+// move parses one move, a repeat block parses to every move it repeats.
+func (p *pathParser) move() ([]gridMove, error) {
+	op := p.s.TokenText()
+	p.next()
+	args, err := p.args(op)
+	if err != nil {
+		return nil, err
+	}
+	// arg returns the i-th argument, or def when it was left out
+	arg := func(i int, def float64) float64 {
+		if i < len(args) {
+			return args[i]
+		}
+		return def
+	}
+
+	var m gridMove
+	switch op {
+	case gridStraight:
+		if len(args) < 1 || len(args) > 2 {
+			return nil, p.errorf("straight takes a distance and an optional speed")
+		}
+		m = gridMove{op: op, distance: args[0], speed: arg(1, gridVel)}
+		if m.distance == 0 {
+			return nil, p.errorf("straight needs a non-zero distance")
+		}
+	case gridSpin:
+		if len(args) < 1 || len(args) > 2 {
+			return nil, p.errorf("spin takes an angle and an optional speed")
+		}
+		m = gridMove{op: op, angle: args[0], speed: arg(1, gridAngVel)}
+		if m.angle == 0 {
+			return nil, p.errorf("spin needs a non-zero angle")
+		}
+	case gridArc:
+		if len(args) < 2 || len(args) > 3 {
+			return nil, p.errorf("arc takes a radius, an angle and an optional speed")
+		}
+		m = gridMove{op: op, radius: args[0], angle: args[1], speed: arg(2, gridVel)}
+		if m.radius <= 0 || m.angle == 0 {
+			return nil, p.errorf("arc needs a positive radius and a non-zero angle")
+		}
+	case gridRepeat:
+		if len(args) != 1 || args[0] < 1 || args[0] != math.Trunc(args[0]) {
+			return nil, p.errorf("repeat takes a positive whole count")
+		}
+		if p.tok != '{' {
+			return nil, p.errorf("repeat needs a { block } of moves")
+		}
+		p.next()
+		block, err := p.path(true)
+		if err != nil {
+			return nil, err
+		}
+		if len(block) == 0 {
+			return nil, p.errorf("repeat needs at least one move in its block")
+		}
+		// the count is compared before it is converted so a huge count can not overflow
+		if args[0] > float64(maxGridMoves/len(block)) {
+			return nil, p.errorf("path has more than %d moves", maxGridMoves)
+		}
+		var moves []gridMove
+		for i := 0; i < int(args[0]); i++ {
+			moves = append(moves, block...)
+		}
+		return moves, nil
+	default:
+		return nil, p.errorf("unknown move %q, expected one of %v", op, []string{gridStraight, gridSpin, gridArc, gridRepeat})
+	}
+	if m.speed <= 0 {
+		return nil, p.errorf("%v needs a positive speed", op)
+	}
+	return []gridMove{m}, nil
+}
+
+// args parses the parenthesized, comma separated numbers after a move.
+func (p *pathParser) args(op string) ([]float64, error) {
+	if p.tok != '(' {
+		return nil, p.errorf("expected ( after %v", op)
+	}
+	p.next()
+	var args []float64
+	for p.tok != ')' {
+		if len(args) > 0 {
+			if p.tok != ',' {
+				return nil, p.errorf("expected , or ) in the arguments of %v", op)
+			}
+			p.next()
+		}
+		neg := false
+		if p.tok == '-' || p.tok == '+' {
+			neg = p.tok == '-'
+			p.next()
+		}
+		if p.tok != scanner.Int && p.tok != scanner.Float {
+			return nil, p.errorf("expected a number in the arguments of %v", op)
+		}
+		v, err := strconv.ParseFloat(p.s.TokenText(), 64)
+		if err != nil {
+			return nil, p.errorf("bad number %q", p.s.TokenText())
+		}
+		if neg {
+			v = -v
+		}
+		args = append(args, v)
+		p.next()
+	}
+	p.next()
+	return args, nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	fileupload "rovercanary/fileUpload"
+
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/robot"
+)
+
+const (
+	machineConfigFile = "machine_config.json"
+	configDiffFile    = "machine_config.diff"
+)
+
+// machineSnapshot is the machine's resource configuration at the start of a run.
+type machineSnapshot struct {
+	// Source is where resource configs came from: app, the local robot, or status when only the robot's status
+	// could be read.
+	Source      string    `json:"source"`
+	Revision    string    `json:"revision"`
+	LastUpdated time.Time `json:"last_updated"`
+	// Incomplete is set when the machine could not be fully read. An incomplete snapshot is kept for reference
+	// but never diffed, its missing resources would show up as removed.
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Resources are keyed by full resource name, e.g. rdk:component:base/sensor_base.
+	Resources map[string]resourceSnapshot `json:"resources"`
+}
+
+// resourceSnapshot is the config and state of one resource.
+type resourceSnapshot struct {
+	Model      string                 `json:"model,omitempty"`
+	State      string                 `json:"state,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// partConfig is the part of a machine's json config the snapshot keeps.
+type partConfig struct {
+	Components []partResource `json:"components"`
+	Services   []partResource `json:"services"`
+}
+
+type partResource struct {
+	Name       string                 `json:"name"`
+	API        string                 `json:"api"`
+	Type       string                 `json:"type"`
+	Model      string                 `json:"model"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// withAPI fills in the api of a config that only names its type.
+func (c partResource) withAPI(fromType func(string) resource.API) partResource {
+	if c.API == "" && c.Type != "" {
+		c.API = fromType(c.Type).String()
+	}
+	return c
+}
+
+// configChange is one value that differs between two snapshots.
+type configChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+func (c configChange) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("+ %v: %v", c.Path, c.New)
+	case c.New == "":
+		return fmt.Sprintf("- %v: %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %v: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// snapshotMachine reads the machine's config revision and resource states, and the resource configs from the
+// local robot when simulating or from app when the part ID and api key are configured.
+func (r *Runner) snapshotMachine(ctx context.Context) (machineSnapshot, error) {
+	snap := machineSnapshot{Source: "status", Resources: map[string]resourceSnapshot{}}
+	if r.machine == nil {
+		return snap, errors.New("no machine to snapshot")
+	}
+
+	status, err := r.machine.MachineStatus(ctx)
+	if err != nil {
+		return snap, err
+	}
+	snap.Revision, snap.LastUpdated = status.Config.Revision, status.Config.LastUpdated
+	for _, res := range status.Resources {
+		snap.Resources[res.Name.String()] = resourceSnapshot{State: res.State.String()}
+	}
+
+	var configs []partResource
+	if local, ok := r.machine.(robot.LocalRobot); ok {
+		snap.Source = "local"
+		cfg := local.Config()
+		for _, c := range append(cfg.Components, cfg.Services...) {
+			configs = append(configs, partResource{Name: c.Name, API: c.API.String(), Model: c.Model.String(), Attributes: c.Attributes})
+		}
+	} else if r.cfg.PartID != "" && r.cfg.APIKey != "" {
+		raw, err := fileupload.GetPartConfig(ctx, r.cfg.PartID, r.cfg.APIKey, r.cfg.APIKeyID, r.logger)
+		if err != nil {
+			return snap, fmt.Errorf("error fetching part config, err = %w", err)
+		}
+		var part partConfig
+		if err := json.Unmarshal([]byte(raw), &part); err != nil {
+			return snap, fmt.Errorf("invalid part config, err = %w", err)
+		}
+		snap.Source = "app"
+		for _, c := range part.Components {
+			configs = append(configs, c.withAPI(resource.APINamespaceRDK.WithComponentType))
+		}
+		for _, c := range part.Services {
+			configs = append(configs, c.withAPI(resource.APINamespaceRDK.WithServiceType))
+		}
+	}
+
+	for _, c := range configs {
+		api, err := resource.NewAPIFromString(c.API)
+		if err != nil {
+			r.logger.Errorf("not snapshotting %v, err = %v", c.Name, err)
+			continue
+		}
+		name := resource.NewName(api, c.Name).String()
+		res := snap.Resources[name]
+		res.Model, res.Attributes = c.Model, c.Attributes
+		snap.Resources[name] = res
+	}
+	return snap, nil
+}
+
+// recordMachineConfig snapshots the machine into the run directory and diffs it against the snapshot of the
+// most recent earlier run that has a complete one. It returns the changes and the ID of the run compared
+// against. An incomplete snapshot is written but not diffed.
+func (r *Runner) recordMachineConfig(ctx context.Context) ([]configChange, string) {
+	snap, snapErr := r.snapshotMachine(ctx)
+	if snapErr != nil {
+		r.logger.Errorf("error reading machine config, snapshot is incomplete, err = %v", snapErr)
+		snap.Incomplete = true
+	}
+	raw, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		r.logger.Errorf("error encoding machine config, err = %v", err)
+		return nil, ""
+	}
+	if err := os.WriteFile(filepath.Join(r.outDir, machineConfigFile), raw, 0o644); err != nil {
+		r.logger.Errorf("error writing machine config, err = %v", err)
+	}
+	if snapErr != nil {
+		r.logger.Infof("not comparing the incomplete machine config against earlier runs")
+		return nil, ""
+	}
+
+	prevDir, prev, err := previousRunWith(filepath.Dir(r.outDir), r.id)
+	if err != nil {
+		r.logger.Infof("no earlier machine config to compare against, err = %v", err)
+		return nil, ""
+	}
+
+	prevID := filepath.Base(prevDir)
+	changes := diffSnapshots(prev, snap)
+	lines := []string{fmt.Sprintf("machine config of run %v compared to run %v", r.id, prevID)}
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	if err := os.WriteFile(filepath.Join(r.outDir, configDiffFile), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		r.logger.Errorf("error writing machine config diff, err = %v", err)
+	}
+	if len(changes) == 0 {
+		r.logger.Infof("machine config unchanged since run %v", prevID)
+	} else {
+		r.logger.Infof("machine config changed since run %v:\n%v", prevID, strings.Join(lines[1:], "\n"))
+	}
+	return changes, prevID
+}
+
+// previousRunWith returns the latest run directory under root before run id that has a complete machine config
+// snapshot, and its snapshot. Snapshots that are incomplete or can not be read are skipped.
+func previousRunWith(root, id string) (string, machineSnapshot, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", machineSnapshot{}, err
+	}
+	// run IDs start with the start time, so they sort chronologically
+	for i := len(entries) - 1; i >= 0; i-- {
+		name := entries[i].Name()
+		if !entries[i].IsDir() || name >= id {
+			continue
+		}
+		dir := filepath.Join(root, name)
+		var snap machineSnapshot
+		raw, err := os.ReadFile(filepath.Join(dir, machineConfigFile))
+		if err == nil {
+			err = json.Unmarshal(raw, &snap)
+		}
+		if err == nil && !snap.Incomplete {
+			return dir, snap, nil
+		}
+	}
+	return "", machineSnapshot{}, fmt.Errorf("no run before %v has a complete %v", id, machineConfigFile)
+}
+
+// diffSnapshots lists every value added, removed or changed between two snapshots, sorted by path. The revision
+// and update time are left out, they change with any edit.
+func diffSnapshots(prev, cur machineSnapshot) []configChange {
+	before, after := map[string]string{}, map[string]string{}
+	flattenConfig("", prev.Resources, before)
+	flattenConfig("", cur.Resources, after)
+
+	var changes []configChange
+	for path, old := range before {
+		if now, ok := after[path]; !ok {
+			changes = append(changes, configChange{Path: path, Old: old})
+		} else if now != old {
+			changes = append(changes, configChange{Path: path, Old: old, New: now})
+		}
+	}
+	for path, now := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, configChange{Path: path, New: now})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// flattenConfig writes every leaf value of v into out, keyed by its dotted path below prefix.
+func flattenConfig(prefix string, v interface{}, out map[string]string) {
+	// round trip through json so structs, attribute maps and slices are walked the same way
+	raw, err := json.Marshal(v)
+	if err != nil {
+		out[prefix] = fmt.Sprint(v)
+		return
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		out[prefix] = string(raw)
+		return
+	}
+	flattenValue(prefix, generic, out)
+}
+
+func flattenValue(prefix string, v interface{}, out map[string]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			flattenValue(join(key), value, out)
+		}
+	case []interface{}:
+		for i, value := range v {
+			flattenValue(join(fmt.Sprint(i)), value, out)
+		}
+	default:
+		raw, _ := json.Marshal(v)
+		out[prefix] = string(raw)
+	}
+}
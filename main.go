@@ -3,16 +3,22 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"math"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
 	fileupload "rovercanary/fileUpload"
+	"rovercanary/plots"
+	"rovercanary/samples"
 	"time"
 
-	"go.uber.org/multierr"
 	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/robot"
 	"go.viam.com/rdk/robot/client"
 	"go.viam.com/utils"
 	"go.viam.com/utils/rpc"
@@ -22,7 +28,6 @@ import (
 	"go.viam.com/rdk/components/movementsensor"
 
 	"github.com/golang/geo/r3"
-	geo "github.com/kellydunn/golang-geo"
 	"go.viam.com/rdk/components/powersensor"
 	"go.viam.com/rdk/logging"
 	rdkutils "go.viam.com/rdk/utils"
@@ -30,649 +35,718 @@ import (
 	"bytes"
 )
 
-var (
-	logger      = logging.NewLogger("client")
-	failedTests = []string{}
-	posExtra    = map[string]interface{}{"return_relative_pos_m": true}
-	startTime   = time.Now()
-)
-
 const (
-	ticksPerRotation   = 1992.0
-	wheelCircumference = 381.0
-	totalTests         = 55
-	tickerDuration     = 100 * time.Millisecond
-	delayBetweenTests  = 1
-	headerString       = "type,linveldes,angveldes,time,posX,posY,theta\n"
-	// replace these constants with your machine's info before running main
-	address  = "<MACHINE-ADDRESS>"
-	apikeyid = "<API-KEY-ID>"
-	apikey   = "<API-KEY>"
-	partID   = "<PART-ID>"
-	webhook  = "<WEBHOOK>"
+	tickerDuration    = 100 * time.Millisecond
+	delayBetweenTests = 1
 )
 
-type baseStruct struct {
-	minLinVel float64
-	minAngVel float64
-	baseTests func(base.Base, movementsensor.MovementSensor, float64, float64, *os.File, *os.File)
-}
-
 func main() {
-	machine, err := client.New(
-		context.Background(),
-		address,
-		logger,
-		client.WithDialOptions(rpc.WithEntityCredentials(
-			apikeyid,
-			rpc.Credentials{
-				Type:    rpc.CredentialsTypeAPIKey,
-				Payload: apikey,
-			})),
-	)
-	if err != nil {
-		logger.Fatal(err)
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
 	}
+	os.Exit(runCanary())
+}
 
-	defer machine.Close(context.Background())
-
-	runTests(machine)
+// runCanary runs the whole canary and returns the process exit code: 0 when every test that ran passed,
+// 1 when any failed or errored.
+func runCanary() int {
+	configPath := flag.String("config", "canary.json", "path to the canary config file (json or yaml)")
+	profileName := flag.String("profile", "", "hardware profile to test against, overrides the config")
+	reportDir := flag.String("report-dir", "./reports", "directory to write the junit xml and json reports to")
+	runsDir := flag.String("runs-dir", "./runs", "directory each run's samples, plots and manifest are written under")
+	simulate := flag.Bool("simulate", false, "run against an in-process robot of rdk fake components instead of a rover")
+	flag.Parse()
 
-	// remove old images before uploading new ones
-	removeAllImages()
+	logger := logging.NewLogger("client")
 
-	cmd := exec.Command("python3", "plot.py")
-	err = cmd.Run()
+	load := loadConfig
+	if *simulate {
+		load = loadSimulatedConfig
+	}
+	cfg, err := load(*configPath)
 	if err != nil {
-		logger.Error(err)
-		return
+		logger.Fatalf("invalid canary config, err = %v", err)
 	}
-
-	// upload all new images
-	uploadAllImages()
-}
-
-// remove each saved image from previous run
-func removeAllImages() {
-	errs := multierr.Combine(
-		os.Remove("./savedImages/sensor_ms_pos.jpg"),
-		os.Remove("./savedImages/sensor_ms_vels.jpg"),
-		os.Remove("./savedImages/sensor_spin_degs.jpg"),
-		os.Remove("./savedImages/sensor_sv_vels.jpg"),
-		os.Remove("./savedImages/wheeled_ms_pos.jpg"),
-		os.Remove("./savedImages/wheeled_ms_vels.jpg"),
-		os.Remove("./savedImages/wheeled_spin_degs.jpg"),
-		os.Remove("./savedImages/wheeled_sv_vels.jpg"),
-		os.Remove("./savedImages/encoded_go_for_rpm.jpg"),
-		os.Remove("./savedImages/encoded_go_for_pos.jpg"),
-		os.Remove("./savedImages/encoded_go_to_rpm.jpg"),
-		os.Remove("./savedImages/encoded_go_to_pos.jpg"),
-		os.Remove("./savedImages/encoded_set_rpm_rpm.jpg"),
-		os.Remove("./savedImages/controlled_go_for_rpm.jpg"),
-		os.Remove("./savedImages/controlled_go_for_pos.jpg"),
-		os.Remove("./savedImages/controlled_go_to_rpm.jpg"),
-		os.Remove("./savedImages/controlled_go_to_pos.jpg"),
-		os.Remove("./savedImages/controlled_set_rpm_rpm.jpg"),
-		os.Remove("./savedImages/grid_test.jpg"),
-	)
-	if errs != nil {
-		logger.Error(errs)
+	profile := simulatedProfile
+	if *simulate {
+		if *profileName != "" {
+			logger.Warnf("ignoring profile %v, simulated runs use the %v profile", *profileName, profileSimulated)
+		}
+	} else {
+		if *profileName != "" {
+			cfg.Profile = *profileName
+		}
+		if profile, err = cfg.hardwareProfile(); err != nil {
+			logger.Fatalf("invalid hardware profile, err = %v", err)
+		}
 	}
-}
 
-// upload all images from current run
-func uploadAllImages() {
-	uploadFiles("./savedImages/sensor_ms_pos.jpg", "SENSOR-BASE", "BASE-MOVESTRAIGHT-POS")
-	uploadFiles("./savedImages/sensor_ms_vels.jpg", "SENSOR-BASE", "BASE-MOVESTRAIGHT-VEL")
-	uploadFiles("./savedImages/sensor_spin_degs.jpg", "SENSOR-BASE", "BASE-SPIN-DEG")
-	uploadFiles("./savedImages/sensor_sv_vels.jpg", "SENSOR-BASE", "BASE-SETVEL-VELS")
-	uploadFiles("./savedImages/wheeled_ms_pos.jpg", "WHEELED-BASE", "BASE-MOVESTRAIGHT-POS")
-	uploadFiles("./savedImages/wheeled_ms_vels.jpg", "WHEELED-BASE", "BASE-MOVESTRAIGHT-VEL")
-	uploadFiles("./savedImages/wheeled_spin_degs.jpg", "WHEELED-BASE", "BASE-SPIN-DEG")
-	uploadFiles("./savedImages/wheeled_sv_vels.jpg", "WHEELED-BASE", "BASE-SETVEL-VELS")
-	uploadFiles("./savedImages/encoded_go_for_rpm.jpg", "ENCODED-MOTOR", "GO-FOR-RPM")
-	uploadFiles("./savedImages/encoded_go_for_pos.jpg", "ENCODED-MOTOR", "GO-FOR-POS")
-	uploadFiles("./savedImages/encoded_go_to_rpm.jpg", "ENCODED-MOTOR", "GO-TO-RPM")
-	uploadFiles("./savedImages/encoded_go_to_pos.jpg", "ENCODED-MOTOR", "GO-TO-POS")
-	uploadFiles("./savedImages/encoded_set_rpm_rpm.jpg", "ENCODED-MOTOR", "SET-RPM")
-	uploadFiles("./savedImages/controlled_go_for_rpm.jpg", "CONTROLLED-MOTOR", "GO-FOR-RPM")
-	uploadFiles("./savedImages/controlled_go_for_pos.jpg", "CONTROLLED-MOTOR", "GO-FOR-POS")
-	uploadFiles("./savedImages/controlled_go_to_rpm.jpg", "CONTROLLED-MOTOR", "GO-TO-RPM")
-	uploadFiles("./savedImages/controlled_go_to_pos.jpg", "CONTROLLED-MOTOR", "GO-TO-POS")
-	uploadFiles("./savedImages/controlled_set_rpm_rpm.jpg", "CONTROLLED-MOTOR", "SET-RPM")
-	uploadFiles("./savedImages/grid_test.jpg", "SENSOR-BASE", "GRID")
-}
-
-// upload a file to viam app
-func uploadFiles(filename, component, testType string) {
-	img, err := os.ReadFile(filename)
+	plan, err := loadPlan(cfg.Plan)
 	if err != nil {
-		logger.Error(err)
-		return
+		logger.Fatalf("invalid test plan, err = %v", err)
 	}
-	bytes := bytes.NewBuffer(img)
-	fileupload.UploadJpeg(context.Background(), bytes, partID, apikey, apikeyid, component, testType, logger)
-}
 
-// create the necessary file for the current test
-func initializeFiles(path string) *os.File {
-	fileLocation := path
-	files, _ := os.ReadDir(fileLocation)
-	runNum := len(files)
-	logger.Infof("number of files %d", runNum)
-	filePath := fmt.Sprintf("%s/run%d.txt", fileLocation, runNum+1)
-	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		logger.Error(err)
-		return nil
+	var machine robot.Robot
+	if *simulate {
+		logger.Info("simulating the rover with fake components")
+		machine, err = newSimulatedRobot(context.Background(), logger, cfg.Components)
+	} else {
+		machine, err = client.New(
+			context.Background(),
+			cfg.Address,
+			logger,
+			client.WithDialOptions(rpc.WithEntityCredentials(
+				cfg.APIKeyID,
+				rpc.Credentials{
+					Type:    rpc.CredentialsTypeAPIKey,
+					Payload: cfg.APIKey,
+				})),
+		)
 	}
-	return f
-}
-
-func runTests(machine *client.RobotClient) {
-	// initialize all necessary components
-	var errs error
-	wheeledBase, err := base.FromRobot(machine, "viam_base")
-	errs = multierr.Combine(errs, err)
-	sensorBase, err := base.FromRobot(machine, "sensor_base")
-	errs = multierr.Combine(errs, err)
-	leftMotor, err := motor.FromRobot(machine, "left")
-	errs = multierr.Combine(errs, err)
-	rightMotor, err := motor.FromRobot(machine, "right")
-	errs = multierr.Combine(errs, err)
-	leftEncoder, err := encoder.FromRobot(machine, "left-enc")
-	errs = multierr.Combine(errs, err)
-	rightEncoder, err := encoder.FromRobot(machine, "right-enc")
-	errs = multierr.Combine(errs, err)
-	odometry, err := movementsensor.FromRobot(machine, "odometry")
-	errs = multierr.Combine(errs, err)
-	powerSensor, err := powersensor.FromRobot(machine, "ina219")
-	errs = multierr.Combine(errs, err)
-	movementSensor, err := movementsensor.FromRobot(machine, "imu")
-	errs = multierr.Combine(errs, err)
-
-	if errs != nil {
-		logger.Errorf("error initializing components, err = %v", errs)
-		return
+	if err != nil {
+		logger.Fatal(err)
 	}
 
-	var sb = baseStruct{
-		minLinVel: 100,
-		minAngVel: 30,
-		baseTests: runBaseTests,
-	}
+	defer machine.Close(context.Background())
 
-	var wb = baseStruct{
-		minLinVel: 100,
-		minAngVel: 30,
-		baseTests: runBaseTests,
+	// from here on errors return so the machine is closed
+	runDir, runID, err := createRunDir(*runsDir, time.Now())
+	if err != nil {
+		logger.Errorf("error creating run directory, err = %v", err)
+		return 1
 	}
+	logger.Infof("starting run %v, writing to %v", runID, runDir)
 
-	startTime = time.Now()
-
-	f := initializeFiles("./wheeledDes")
-	defer f.Close()
-	f2 := initializeFiles("./wheeledData")
-	defer f2.Close()
-
-	f.WriteString(headerString)
-	f2.WriteString(headerString)
-
-	// wheeled base tests
-	logger.Info("Starting wheeled base tests...")
-	wb.baseTests(wheeledBase, odometry, wb.minLinVel, wb.minAngVel, f, f2)
-
-	f3 := initializeFiles("./sensorDes")
-	defer f3.Close()
-	f4 := initializeFiles("./sensorData")
-	defer f4.Close()
-
-	f3.WriteString(headerString)
-	f4.WriteString(headerString)
-
-	// sensor base tests
-	logger.Info("Starting sensor controlled base tests...")
-	sb.baseTests(sensorBase, odometry, sb.minLinVel, sb.minAngVel, f3, f4)
-
-	f5 := initializeFiles("./encodedDes")
-	defer f5.Close()
-	f6 := initializeFiles("./encodedData")
-	defer f6.Close()
-
-	f5.WriteString(headerString)
-	f6.WriteString(headerString)
-
-	// encoded motor tests
-	logger.Info("Starting encoded motor tests...")
-	runMotorTests(leftMotor, odometry, f5, f6)
+	r := newRunner(logger, machine, cfg, plan, profile, runDir)
 
-	f7 := initializeFiles("./controlledDes")
-	defer f7.Close()
-	f8 := initializeFiles("./controlledData")
-	defer f8.Close()
+	// stop every actuator the run touched if it is interrupted or panics
+	stopWatching := r.safety.watchSignals()
+	defer stopWatching()
+	defer r.safety.recoverPanic()
 
-	f7.WriteString(headerString)
-	f8.WriteString(headerString)
+	r.runTests(context.Background())
+	images := r.plotRun()
 
-	// controlled motor tests
-	logger.Info("Starting controlled motor tests...")
-	runMotorTests(rightMotor, odometry, f7, f8)
-
-	// single encoder tests
-	logger.Info("Starting encoder tests...")
-	runEncoderTests(leftMotor, leftEncoder)
-	runEncoderTests(rightMotor, rightEncoder)
+	report := newRunReport(r.start, time.Now(), profile, r.env, r.results)
+	var reports []string
+	junitPath, jsonPath, err := writeReports(*reportDir, r.id, report)
+	if err != nil {
+		logger.Errorf("error writing reports, err = %v", err)
+	} else {
+		logger.Infof("wrote reports %v and %v", junitPath, jsonPath)
+		reports = append(reports, junitPath, jsonPath)
+	}
 
-	// power sensor tests
-	logger.Info("Starting power sensor tests...")
-	runPowerSensorTests(powerSensor)
+	if !*simulate && len(images) != 0 {
+		// upload all new images
+		r.uploadAllImages()
+	}
 
-	// movement sensor tests
-	logger.Info("Starting movement sensor tests...")
-	runMovementSensorTests(movementSensor)
+	manifest := newManifest(r, report.End, *simulate, report.Passed)
+	manifestPath, err := writeManifest(r.outDir, manifest, reports...)
+	if err != nil {
+		logger.Errorf("error writing manifest, err = %v", err)
+	} else {
+		logger.Infof("wrote manifest %v", manifestPath)
+	}
 
-	f9 := initializeFiles("./gridDes")
-	defer f9.Close()
-	f10 := initializeFiles("./gridData")
-	defer f10.Close()
+	// notify last so the reports are written even if slack can't be reached
+	r.notify()
 
-	f9.WriteString(headerString)
-	f10.WriteString(headerString)
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
 
-	// grid tests
-	logger.Info("Starting grid test with sensor controlled base...")
-	runGridTest(sensorBase, odometry, f9, f10)
+// plotSuites are the suites plotted after a run, with the sample file prefix, plot title and plot function of
+// each.
+var plotSuites = []struct {
+	suite  string
+	prefix string
+	name   string
+	plot   plots.Func
+}{
+	{suiteWheeledBase, "wheeled", "Wheeled Base", plots.Base},
+	{suiteSensorBase, "sensor", "Sensor Base", plots.Base},
+	{suiteEncodedMotor, "encoded", "Encoded Motor", plots.Motor},
+	{suiteControlledMotor, "controlled", "Controlled Motor", plots.Motor},
+	{suiteGrid, "grid", "Sensor Base", plots.Grid},
+}
 
-	if len(failedTests) != 0 {
-		message := "tests failed: " + fmt.Sprint(len(failedTests)) + "/" + fmt.Sprint(totalTests) + "\n"
-		for i := 0; i < len(failedTests); i++ {
-			message += "- " + (failedTests[i] + "\n")
+// plotRun plots the samples of every suite that recorded them and returns the images written. A suite whose
+// plots cannot all be drawn gets an errored plot result.
+func (r *Runner) plotRun() []string {
+	components := map[string]string{
+		suiteWheeledBase:     r.cfg.Components.WheeledBase,
+		suiteSensorBase:      r.cfg.Components.SensorBase,
+		suiteEncodedMotor:    r.cfg.Components.LeftMotor,
+		suiteControlledMotor: r.cfg.Components.RightMotor,
+		suiteGrid:            r.cfg.Components.SensorBase,
+	}
+	var images []string
+	for _, s := range plotSuites {
+		// suites that were skipped have no samples to plot
+		if _, err := os.Stat(filepath.Join(r.outDir, s.prefix+"Des.jsonl")); err != nil {
+			continue
+		}
+		res := newResult(s.suite, components[s.suite], "plot", nil)
+		paths, err := s.plot(r.outDir, s.prefix, s.name)
+		if err != nil {
+			r.logger.Errorf("error plotting %v, err = %v", s.suite, err)
 		}
-		sendSlackMessage(message)
+		res.finish(err)
+		r.results.add(res)
+		images = append(images, paths...)
 	}
+	return images
 }
 
-func runBaseTests(b base.Base, odometry movementsensor.MovementSensor, minLinVel, minAngVel float64, f, f2 *os.File) {
-	// SetVelocity: linear = minLinVel mm/s, angular = 0 deg/sec
-	if err := setVelocityTest(b, odometry, r3.Vector{Y: minLinVel}, r3.Vector{}, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+// upload all images from current run
+func (r *Runner) uploadAllImages() {
+	r.uploadFiles(filepath.Join(r.outDir, "sensor_ms_pos.jpg"), "SENSOR-BASE", "BASE-MOVESTRAIGHT-POS")
+	r.uploadFiles(filepath.Join(r.outDir, "sensor_ms_vels.jpg"), "SENSOR-BASE", "BASE-MOVESTRAIGHT-VEL")
+	r.uploadFiles(filepath.Join(r.outDir, "sensor_spin_degs.jpg"), "SENSOR-BASE", "BASE-SPIN-DEG")
+	r.uploadFiles(filepath.Join(r.outDir, "sensor_sv_vels.jpg"), "SENSOR-BASE", "BASE-SETVEL-VELS")
+	r.uploadFiles(filepath.Join(r.outDir, "wheeled_ms_pos.jpg"), "WHEELED-BASE", "BASE-MOVESTRAIGHT-POS")
+	r.uploadFiles(filepath.Join(r.outDir, "wheeled_ms_vels.jpg"), "WHEELED-BASE", "BASE-MOVESTRAIGHT-VEL")
+	r.uploadFiles(filepath.Join(r.outDir, "wheeled_spin_degs.jpg"), "WHEELED-BASE", "BASE-SPIN-DEG")
+	r.uploadFiles(filepath.Join(r.outDir, "wheeled_sv_vels.jpg"), "WHEELED-BASE", "BASE-SETVEL-VELS")
+	r.uploadFiles(filepath.Join(r.outDir, "encoded_go_for_rpm.jpg"), "ENCODED-MOTOR", "GO-FOR-RPM")
+	r.uploadFiles(filepath.Join(r.outDir, "encoded_go_for_pos.jpg"), "ENCODED-MOTOR", "GO-FOR-POS")
+	r.uploadFiles(filepath.Join(r.outDir, "encoded_go_to_rpm.jpg"), "ENCODED-MOTOR", "GO-TO-RPM")
+	r.uploadFiles(filepath.Join(r.outDir, "encoded_go_to_pos.jpg"), "ENCODED-MOTOR", "GO-TO-POS")
+	r.uploadFiles(filepath.Join(r.outDir, "encoded_set_rpm_rpm.jpg"), "ENCODED-MOTOR", "SET-RPM")
+	r.uploadFiles(filepath.Join(r.outDir, "controlled_go_for_rpm.jpg"), "CONTROLLED-MOTOR", "GO-FOR-RPM")
+	r.uploadFiles(filepath.Join(r.outDir, "controlled_go_for_pos.jpg"), "CONTROLLED-MOTOR", "GO-FOR-POS")
+	r.uploadFiles(filepath.Join(r.outDir, "controlled_go_to_rpm.jpg"), "CONTROLLED-MOTOR", "GO-TO-RPM")
+	r.uploadFiles(filepath.Join(r.outDir, "controlled_go_to_pos.jpg"), "CONTROLLED-MOTOR", "GO-TO-POS")
+	r.uploadFiles(filepath.Join(r.outDir, "controlled_set_rpm_rpm.jpg"), "CONTROLLED-MOTOR", "SET-RPM")
+	r.uploadFiles(filepath.Join(r.outDir, "grid_test.jpg"), "SENSOR-BASE", "GRID")
+}
 
-	// SetVelocity: linear = 250 mm/s, angular = 0 deg/sec
-	if err := setVelocityTest(b, odometry, r3.Vector{Y: -250.0}, r3.Vector{}, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+// upload a file to viam app
+func (r *Runner) uploadFiles(filename, component, testType string) {
+	img, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		// the suite did not run
+		return
 	}
-	time.Sleep(delayBetweenTests * time.Second)
-
-	// SetVelocity: linear = 0 mm/s, angular = minAngVel deg/sec
-	if err := setVelocityTest(b, odometry, r3.Vector{}, r3.Vector{Z: -minAngVel}, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+	if err != nil {
+		r.logger.Error(err)
+		return
 	}
-	time.Sleep(delayBetweenTests * time.Second)
+	bytes := bytes.NewBuffer(img)
+	fileupload.UploadJpeg(context.Background(), bytes, r.cfg.PartID, r.cfg.APIKey, r.cfg.APIKeyID, component, testType, r.env.tags(), r.logger)
+}
 
-	// SetVelocity: linear = 0 mm/s, angular = 90 deg/sec
-	if err := setVelocityTest(b, odometry, r3.Vector{}, r3.Vector{Z: 90.0}, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+// create the desired and measured sample files for a suite in the run directory, samples of a file that
+// cannot be created are discarded
+func (r *Runner) initializeFiles(name, suite string) (*samples.Writer, *samples.Writer, func()) {
+	var files []*os.File
+	open := func(file string, kind samples.Kind) *samples.Writer {
+		f, err := os.Create(filepath.Join(r.outDir, file))
+		if err == nil {
+			files = append(files, f)
+			var w *samples.Writer
+			if w, err = samples.NewWriter(f, r.id, suite, kind); err == nil {
+				return w
+			}
+		}
+		r.logger.Error(err)
+		w, _ := samples.NewWriter(io.Discard, r.id, suite, kind)
+		return w
+	}
+	des := open(name+"Des.jsonl", samples.Desired)
+	data := open(name+"Data.jsonl", samples.Measured)
+	return des, data, func() {
+		for _, f := range files {
+			f.Close()
+		}
 	}
-	time.Sleep(delayBetweenTests * time.Second)
+}
 
-	// SetVelocity: linear = 200 mm/s, angular = 45 deg/sec
-	if err := setVelocityTest(b, odometry, r3.Vector{Y: 200.0}, r3.Vector{Z: 45.0}, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+// canaryComponents holds the components resolved for a run. A nil field means its role was
+// not configured or could not be found, and every suite that needs it is skipped.
+type canaryComponents struct {
+	wheeledBase    base.Base
+	sensorBase     base.Base
+	leftMotor      motor.Motor
+	rightMotor     motor.Motor
+	leftEncoder    encoder.Encoder
+	rightEncoder   encoder.Encoder
+	odometry       movementsensor.MovementSensor
+	powerSensor    powersensor.PowerSensor
+	movementSensor movementsensor.MovementSensor
+}
 
-	// SetVelocity: linear = 200 mm/s -> linear = minLinVel mm/s
-	if err := consecutiveVelocityTest(b, odometry, r3.Vector{Y: 200.0}, r3.Vector{Y: minLinVel}, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+// resolveComponents looks up every configured role on the machine. Roles without a name are
+// left nil; roles that fail to resolve are recorded as failed tests and also left nil.
+func (r *Runner) resolveComponents() canaryComponents {
+	machine, names := r.machine, r.cfg.Components
+	var c canaryComponents
+	resolve := func(role, name string, fromRobot func(string) error) {
+		if name == "" {
+			r.logger.Infof("%v is not configured, skipping its tests", role)
+			return
+		}
+		if err := fromRobot(name); err != nil {
+			r.logger.Errorf("error initializing %v %q, err = %v", role, name, err)
+			res := newResult(suiteSetup, name, "resolve", nil)
+			res.finish(err)
+			r.results.add(res)
+		}
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// MoveStraight: distance = 100 mm, speed = 50 mm/sec
-	if err := moveStraightTest(b, odometry, 100, 50, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	resolve("wheeled_base", names.WheeledBase, func(name string) (err error) {
+		c.wheeledBase, err = base.FromRobot(machine, name)
+		return err
+	})
+	resolve("sensor_base", names.SensorBase, func(name string) (err error) {
+		c.sensorBase, err = base.FromRobot(machine, name)
+		return err
+	})
+	resolve("left_motor", names.LeftMotor, func(name string) (err error) {
+		c.leftMotor, err = motor.FromRobot(machine, name)
+		return err
+	})
+	resolve("right_motor", names.RightMotor, func(name string) (err error) {
+		c.rightMotor, err = motor.FromRobot(machine, name)
+		return err
+	})
+	resolve("left_encoder", names.LeftEncoder, func(name string) (err error) {
+		c.leftEncoder, err = encoder.FromRobot(machine, name)
+		return err
+	})
+	resolve("right_encoder", names.RightEncoder, func(name string) (err error) {
+		c.rightEncoder, err = encoder.FromRobot(machine, name)
+		return err
+	})
+	resolve("odometry", names.Odometry, func(name string) (err error) {
+		c.odometry, err = movementsensor.FromRobot(machine, name)
+		return err
+	})
+	resolve("power_sensor", names.PowerSensor, func(name string) (err error) {
+		c.powerSensor, err = powersensor.FromRobot(machine, name)
+		return err
+	})
+	resolve("movement_sensor", names.MovementSensor, func(name string) (err error) {
+		c.movementSensor, err = movementsensor.FromRobot(machine, name)
+		return err
+	})
+	return c
+}
 
-	// MoveStraight: distance = -100 mm, speed = 250 mm/sec
-	if err := moveStraightTest(b, odometry, -100, 250, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+// canRun reports whether every component a suite needs was resolved, recording a skipped result otherwise.
+func (r *Runner) canRun(suite string, deps ...resource.Resource) bool {
+	for _, dep := range deps {
+		if dep == nil {
+			r.logger.Infof("skipping %v tests, a required component is not configured", suite)
+			res := newResult(suite, "", "suite", nil)
+			res.skip("a required component is not configured")
+			r.results.add(res)
+			return false
+		}
 	}
-	time.Sleep(delayBetweenTests * time.Second)
+	return true
+}
 
-	// MoveStraight: distance = 1000 mm, speed = -50 mm/sec
-	if err := moveStraightTest(b, odometry, 1000, -50, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+// runTests runs every suite the configured components allow.
+func (r *Runner) runTests(ctx context.Context) {
+	// initialize all configured components
+	c := r.resolveComponents()
+	r.encoders = wheelEncoders{left: c.leftEncoder, right: c.rightEncoder}
 
-	// MoveStraight: distance = -1000 mm, speed = -250 mm/sec
-	if err := moveStraightTest(b, odometry, -1000, -250, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	r.env = r.collectEnvironment(ctx)
+	r.logger.Infof("testing viam-server %v (api %v) with canary %v, %d resources",
+		r.env.ServerVersion, r.env.APIVersion, r.env.CanaryRevision, len(r.env.Resources))
+	r.configChanges, r.prevRun = r.recordMachineConfig(ctx)
 
-	// Spin: distance = 40 deg, speed = 20 deg/sec
-	if err := spinTest(b, odometry, 40, 20, false, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	r.start = time.Now()
+	r.logger.Infof("testing against hardware profile %v", r.profile.Name)
 
-	// Spin: distance = 40 deg, speed = -60 deg/sec
-	if err := spinTest(b, odometry, 40, -60, false, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+	// check the battery and imu before anything moves
+	r.logger.Info("Starting pre-flight checks...")
+	precondition := r.preflight(ctx, c.powerSensor, c.movementSensor)
+	if precondition != nil {
+		r.logger.Errorf("pre-flight failed, skipping motion suites, err = %v", precondition)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// Spin: distance = -360 deg, speed = 20 deg/sec
-	if err := spinTest(b, odometry, -360, 20, true, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	// wheeled base tests
+	if r.canMove(suiteWheeledBase, precondition, c.wheeledBase, c.odometry) {
+		des, data, closeFiles := r.initializeFiles("wheeled", suiteWheeledBase)
+		defer closeFiles()
 
-	// Spin: distance = -360 deg, speed = -90 deg/sec
-	if err := spinTest(b, odometry, -360, -90, true, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+		r.logger.Info("Starting wheeled base tests...")
+		r.runBaseTests(ctx, suiteWheeledBase, c.wheeledBase, c.odometry, r.plan.Base, des, data)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// SetPower: power = 10% / Stop
-	if err := baseSetPowerTest(b, odometry, 0.1); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	// sensor base tests
+	if r.canMove(suiteSensorBase, precondition, c.sensorBase, c.odometry) {
+		des, data, closeFiles := r.initializeFiles("sensor", suiteSensorBase)
+		defer closeFiles()
 
-	// SetPower: power = 90% / Stop
-	if err := baseSetPowerTest(b, odometry, 0.9); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+		r.logger.Info("Starting sensor controlled base tests...")
+		r.runBaseTests(ctx, suiteSensorBase, c.sensorBase, c.odometry, r.plan.Base, des, data)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// SetPower: power = -50% / Stop
-	if err := baseSetPowerTest(b, odometry, -0.5); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", b.Name().ShortName(), err))
+	// encoded motor tests
+	if r.canMove(suiteEncodedMotor, precondition, c.leftMotor) {
+		des, data, closeFiles := r.initializeFiles("encoded", suiteEncodedMotor)
+		defer closeFiles()
+
+		r.logger.Info("Starting encoded motor tests...")
+		r.runMotorTests(ctx, suiteEncodedMotor, c.leftMotor, c.odometry, r.plan.Motor, des, data)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-}
+	// controlled motor tests
+	if r.canMove(suiteControlledMotor, precondition, c.rightMotor) {
+		des, data, closeFiles := r.initializeFiles("controlled", suiteControlledMotor)
+		defer closeFiles()
 
-func runMotorTests(m motor.Motor, odometry movementsensor.MovementSensor, f, f2 *os.File) {
-	// GoFor: distance = 1 rev, speed = 10 rpm
-	if err := goForTest(m, odometry, 10, 1, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+		r.logger.Info("Starting controlled motor tests...")
+		r.runMotorTests(ctx, suiteControlledMotor, c.rightMotor, c.odometry, r.plan.Motor, des, data)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// GoFor: distance = -5 rev, speed = 50 rpm
-	if err := goForTest(m, odometry, 50, -5, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+	// single encoder tests
+	r.logger.Info("Starting encoder tests...")
+	if r.canMove(suiteEncoder, precondition, c.leftMotor, c.leftEncoder) {
+		r.runEncoderTests(ctx, c.leftMotor, c.leftEncoder)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
-
-	// GoFor: distance = 5 rev, speed = -10 rpm
-	if err := goForTest(m, odometry, -10, 5, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+	if r.canMove(suiteEncoder, precondition, c.rightMotor, c.rightEncoder) {
+		r.runEncoderTests(ctx, c.rightMotor, c.rightEncoder)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// GoFor: distance = -5 rev, speed = -50 rpm
-	if err := goForTest(m, odometry, -50, -5, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+	// power sensor tests
+	if r.canRun(suitePowerSensor, c.powerSensor) {
+		r.logger.Info("Starting power sensor tests...")
+		r.runPowerSensorTests(ctx, c.powerSensor)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// GoTo: position = -5, speed = 50 rpm, ResetZeroPosition: offset = -2
-	if err := goToTest(m, odometry, 50, -5, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+	// movement sensor tests
+	if r.canRun(suiteMovementSensor, c.movementSensor) {
+		r.logger.Info("Starting movement sensor tests...")
+		r.runMovementSensorTests(ctx, c.movementSensor)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// GoTo: position = 0, speed = 10 rpm
-	if err := goToTest(m, odometry, 10, 0, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	// grid tests
+	if r.canMove(suiteGrid, precondition, c.sensorBase, c.odometry) {
+		des, data, closeFiles := r.initializeFiles("grid", suiteGrid)
+		defer closeFiles()
 
-	// SetRPM: speed = 10 rpm
-	if err := setRPMTest(m, odometry, 10, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+		r.logger.Info("Starting grid test with sensor controlled base...")
+		r.runGridTest(ctx, c.sensorBase, c.odometry, des, data)
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 
-	// SetRPM: speed = -50 rpm
-	if err := setRPMTest(m, odometry, -50, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
-	}
-	time.Sleep(delayBetweenTests * time.Second)
+	summary := r.results.summary()
+	r.logger.Infof("%d tests ran: %d passed, %d failed, %d errored, %d skipped",
+		summary.Total, summary.Passed, summary.Failed, summary.Errored, summary.Skipped)
+}
 
-	// SetRPM: rpm = 30 -> rpm = 60
-	if err := consecutiveRPMTest(m, odometry, 30, 60, f, f2); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+// notify reports unsuccessful tests, including plots that could not be drawn, and failed pre-flight checks to
+// slack.
+func (r *Runner) notify() {
+	if message := r.results.slackMessage(r.env); message != "" && r.cfg.Webhook != "" {
+		if len(r.configChanges) != 0 {
+			message += fmt.Sprintf("machine config changed since run %v: %d changes, see %v\n", r.prevRun, len(r.configChanges), configDiffFile)
+		}
+		if err := r.sendSlackMessage(r.cfg.Webhook, message); err != nil {
+			r.logger.Errorf("error sending slack message, err = %v", err)
+		}
 	}
-	time.Sleep(delayBetweenTests * time.Second)
+}
 
-	// SetPower: power = 10% / Stop
-	if err := motorSetPowerTest(m, 0.1); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+// runBaseTests runs every base step in the plan, recording a result for each.
+func (r *Runner) runBaseTests(ctx context.Context, suite string, b base.Base, odometry movementsensor.MovementSensor, steps []planStep, des, data *samples.Writer) {
+	r.safety.track(b)
+	for i, step := range steps {
+		res := newResult(suite, b.Name().ShortName(), step.Op, step.params())
+		res.Case = caseID(i, step.Op)
+		res.Estimator = step.estimator()
+		des.SetCase(res.Case)
+		data.SetCase(res.Case)
+		err := runWithTimeout(ctx, step.timeout(), func(ctx context.Context) error {
+			return step.runBase(ctx, r, b, odometry, res, des, data)
+		})
+		if err != nil {
+			// the step may have returned before stopping the base
+			r.safety.stopAll(fmt.Sprintf("%v %v returned an error", b.Name().ShortName(), step.Op))
+		}
+		res.finish(err)
+		r.results.add(res)
+		time.Sleep(step.delay())
 	}
-	time.Sleep(delayBetweenTests * time.Second)
+}
 
-	// SetPower: power = -90% / Stop
-	if err := motorSetPowerTest(m, -0.9); err != nil {
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", m.Name().ShortName(), err))
+// runMotorTests runs every motor step in the plan, recording a result for each.
+func (r *Runner) runMotorTests(ctx context.Context, suite string, m motor.Motor, odometry movementsensor.MovementSensor, steps []planStep, des, data *samples.Writer) {
+	r.safety.track(m)
+	for i, step := range steps {
+		res := newResult(suite, m.Name().ShortName(), step.Op, step.params())
+		res.Case = caseID(i, step.Op)
+		res.Estimator = step.estimator()
+		des.SetCase(res.Case)
+		data.SetCase(res.Case)
+		err := runWithTimeout(ctx, step.timeout(), func(ctx context.Context) error {
+			return step.runMotor(ctx, r, m, odometry, res, des, data)
+		})
+		if err != nil {
+			// the step may have returned before stopping the motor
+			r.safety.stopAll(fmt.Sprintf("%v %v returned an error", m.Name().ShortName(), step.Op))
+		}
+		res.finish(err)
+		r.results.add(res)
+		time.Sleep(step.delay())
 	}
-	time.Sleep(delayBetweenTests * time.Second)
 }
 
-func runEncoderTests(m motor.Motor, enc encoder.Encoder) {
+func (r *Runner) runEncoderTests(ctx context.Context, m motor.Motor, enc encoder.Encoder) {
+	res := newResult(suiteEncoder, enc.Name().ShortName(), "position", nil)
+	res.finish(runWithTimeout(ctx, sensorTestTimeout, func(ctx context.Context) error {
+		return r.encoderPositionTest(ctx, m, enc, res)
+	}))
+	r.results.add(res)
+
+	res = newResult(suiteEncoder, enc.Name().ShortName(), "reset_position", nil)
+	res.finish(runWithTimeout(ctx, sensorTestTimeout, func(ctx context.Context) error {
+		return r.encoderResetTest(ctx, m, enc, res)
+	}))
+	r.results.add(res)
+}
+
+func (r *Runner) encoderPositionTest(ctx context.Context, m motor.Motor, enc encoder.Encoder, res *testResult) error {
+	encoderErr := "error comparing encoder position to motor position, err = %v"
 	// reset motor position to match encoder position
-	if err := m.ResetZeroPosition(context.Background(), 0, nil); err != nil {
-		logger.Error(err)
-		return
+	if err := m.ResetZeroPosition(ctx, 0, nil); err != nil {
+		return fmt.Errorf(encoderErr, err)
 	}
 
 	// motor position
-	pos, err := m.Position(context.Background(), nil)
+	pos, err := m.Position(ctx, nil)
 	if err != nil {
-		logger.Error(err)
+		return fmt.Errorf(encoderErr, err)
 	}
 
 	// encoder positon
-	ticks, _, err := enc.Position(context.Background(), 0, nil)
+	ticks, _, err := enc.Position(ctx, 0, nil)
 	if err != nil {
-		logger.Error(err)
+		return fmt.Errorf(encoderErr, err)
 	}
 
 	// verify ticks is approximately motor position
-	if !rdkutils.Float64AlmostEqual(ticks, pos*ticksPerRotation, 10) {
-		storeErr := fmt.Errorf("measured encoder position %v did not equal motor position %v", ticks, pos*ticksPerRotation)
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", enc.Name().ShortName(), storeErr))
+	if !res.check("encoder ticks", ticks, pos*r.profile.TicksPerRotation, 10) {
+		return fmt.Errorf(encoderErr, fmt.Sprintf("measured encoder position %v did not equal motor position %v", ticks, pos*r.profile.TicksPerRotation))
 	}
+	return nil
+}
 
+func (r *Runner) encoderResetTest(ctx context.Context, m motor.Motor, enc encoder.Encoder, res *testResult) error {
+	resetErr := "error resetting encoder position, err = %v"
 	// reset position
-	if err := enc.ResetPosition(context.Background(), nil); err != nil {
-		logger.Error(err)
+	if err := enc.ResetPosition(ctx, nil); err != nil {
+		return fmt.Errorf(resetErr, err)
 	}
 
 	// motor position
-	pos, err = m.Position(context.Background(), nil)
+	pos, err := m.Position(ctx, nil)
 	if err != nil {
-		logger.Error(err)
+		return fmt.Errorf(resetErr, err)
 	}
 
 	// encoder positon
-	ticks, _, err = enc.Position(context.Background(), 0, nil)
+	ticks, _, err := enc.Position(ctx, 0, nil)
 	if err != nil {
-		logger.Error(err)
+		return fmt.Errorf(resetErr, err)
 	}
 
 	// verify ticks and motor position are zero
-	if ticks != pos*ticksPerRotation || ticks != 0.0 {
-		storeErr := fmt.Errorf("measured encoder position %v did not equal motor position %v", ticks, pos*ticksPerRotation)
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", enc.Name().ShortName(), storeErr))
+	matchesMotor := res.check("encoder ticks", ticks, pos*r.profile.TicksPerRotation, 0)
+	isZero := res.check("encoder ticks after reset", ticks, 0, 0)
+	if !matchesMotor || !isZero {
+		return fmt.Errorf(resetErr, fmt.Sprintf("measured encoder position %v did not equal motor position %v", ticks, pos*r.profile.TicksPerRotation))
 	}
+	return nil
 }
 
-func runPowerSensorTests(ps powersensor.PowerSensor) {
-	volts, _, err := ps.Voltage(context.Background(), nil)
-	if err != nil {
-		logger.Error(err)
-	}
-
-	current, _, err := ps.Current(context.Background(), nil)
-	if err != nil {
-		logger.Error(err)
-	}
-
-	power, err := ps.Power(context.Background(), nil)
-	if err != nil {
-		logger.Error(err)
-	}
-
-	// verify voltage is ~15.2
-	if !rdkutils.Float64AlmostEqual(volts, 15.2, 1.5) {
-		storeErr := fmt.Errorf("voltage does not equal 15.2, voltage = %v", volts)
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", ps.Name().ShortName(), storeErr))
-	}
-
-	// verify current is ~0.29
-	if !rdkutils.Float64AlmostEqual(current, 0.29, 0.15) {
-		storeErr := fmt.Errorf("current does not equal 0.29, current = %v", current)
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", ps.Name().ShortName(), storeErr))
-	}
-
-	// verify power is ~4.4
-	if !rdkutils.Float64AlmostEqual(power, 4.4, 1.5) {
-		storeErr := fmt.Errorf("power does not equal 4.4, power = %v", power)
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", ps.Name().ShortName(), storeErr))
-	}
+func (r *Runner) runPowerSensorTests(ctx context.Context, ps powersensor.PowerSensor) {
+	ctx, cancel := context.WithTimeout(ctx, sensorTestTimeout)
+	defer cancel()
+	name := ps.Name().ShortName()
+
+	// verify voltage is ~nominal voltage
+	res := newResult(suitePowerSensor, name, "voltage", nil)
+	volts, _, err := ps.Voltage(ctx, nil)
+	if err == nil && !res.check("voltage", volts, r.profile.Voltage, r.profile.VoltageTolerance) {
+		err = fmt.Errorf("voltage does not equal %v, voltage = %v", r.profile.Voltage, volts)
+	}
+	res.finish(err)
+	r.results.add(res)
+
+	// verify current is ~nominal current
+	res = newResult(suitePowerSensor, name, "current", nil)
+	current, _, err := ps.Current(ctx, nil)
+	if err == nil && !res.check("current", current, r.profile.Current, r.profile.CurrentTolerance) {
+		err = fmt.Errorf("current does not equal %v, current = %v", r.profile.Current, current)
+	}
+	res.finish(err)
+	r.results.add(res)
+
+	// verify power is ~nominal power
+	res = newResult(suitePowerSensor, name, "power", nil)
+	power, err := ps.Power(ctx, nil)
+	if err == nil && !res.check("power", power, r.profile.Power, r.profile.PowerTolerance) {
+		err = fmt.Errorf("power does not equal %v, power = %v", r.profile.Power, power)
+	}
+	res.finish(err)
+	r.results.add(res)
 }
 
-func runMovementSensorTests(ms movementsensor.MovementSensor) {
-	linearAccel, err := ms.LinearAcceleration(context.Background(), nil)
-	if err != nil {
-		logger.Error(err)
-	}
-
-	// verify linear acceleration is ~9.81
-	if !rdkutils.Float64AlmostEqual(linearAccel.Z, 9.81, 9.81*0.5) {
-		storeErr := fmt.Errorf("linear acceleration is not ~9.81, linear acceleration = %v", linearAccel.Z)
-		failedTests = append(failedTests, fmt.Sprintf("%v: %v", ms.Name().ShortName(), storeErr))
-	}
+func (r *Runner) runMovementSensorTests(ctx context.Context, ms movementsensor.MovementSensor) {
+	ctx, cancel := context.WithTimeout(ctx, sensorTestTimeout)
+	defer cancel()
+	// verify linear acceleration is ~gravity
+	res := newResult(suiteMovementSensor, ms.Name().ShortName(), "linear_acceleration", nil)
+	linearAccel, err := ms.LinearAcceleration(ctx, nil)
+	if err == nil && !res.check("linear acceleration z", linearAccel.Z, r.profile.Gravity, r.profile.Gravity*gravityTolerance) {
+		err = fmt.Errorf("linear acceleration is not ~%v, linear acceleration = %v", r.profile.Gravity, linearAccel.Z)
+	}
+	res.finish(err)
+	r.results.add(res)
 }
 
-func setVelocityTest(b base.Base, odometry movementsensor.MovementSensor, linear, angular r3.Vector, des, data *os.File) error {
+func (r *Runner) setVelocityTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, linear, angular r3.Vector, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	setVelocityErr := fmt.Sprintf("error setting velocity to linear = %v mm/s and anguar = %v deg/sec", linear.Y, angular.Z)
 	setVelocityErr += ", err = %v"
-	if err := b.SetVelocity(context.Background(), linear, angular, nil); err != nil {
+	resp, stopRecording := recordResponse(des, data)
+	defer stopRecording()
+
+	if err := b.SetVelocity(ctx, linear, angular, nil); err != nil {
 		return fmt.Errorf(setVelocityErr, err)
 	}
+	des.Write(samples.Record{Type: samples.SetVelocity, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear.Y, AngularVelocity: angular.Z})
 
-	// let the base get up to speed for 5 seconds
-	time.Sleep(5 * time.Second)
+	// let the base get up to speed
+	if err := r.settle(ctx, odometry, nil, tol.settle, data, samples.SetVelocity); err != nil {
+		return fmt.Errorf(setVelocityErr, err)
+	}
 
 	// goal velocity start
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v\n", "sv", linear.Y, angular.Z, time.Since(startTime).Milliseconds()))
+	des.Write(samples.Record{Type: samples.SetVelocity, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear.Y, AngularVelocity: angular.Z})
 
-	sampleCtx, cancel := context.WithCancel(context.Background())
-	linEst, angEst := sampleEverything(sampleCtx, odometry, nil, linear.Y, angular.Z, 5, data, "sv", cancel)
+	sampleCtx, cancel := context.WithCancel(ctx)
+	linEst, angEst := r.sampleEverything(sampleCtx, odometry, nil, linear.Y, angular.Z, tol.sample.Seconds(), tol.estimator, data, samples.SetVelocity, cancel)
 	cancel()
 
 	// goal velocity end
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v\n", "sv", linear.Y, angular.Z, time.Since(startTime).Milliseconds()))
+	des.Write(samples.Record{Type: samples.SetVelocity, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear.Y, AngularVelocity: angular.Z})
 
-	if err := b.Stop(context.Background(), nil); err != nil {
+	if err := b.Stop(ctx, nil); err != nil {
 		return fmt.Errorf(setVelocityErr, err)
 	}
 
-	linearErr := 50.0
-	if linear.Y != 0.0 {
-		linearErr = math.Abs(linear.Y) * 0.5
-	}
-
-	angularErr := 15.0
-	if angular.Z != 0.0 {
-		angularErr = math.Abs(angular.Z) * 0.5
+	stepErr := checkSteps(res, resp.des, resp.data, velocityFields, tol)
+	if err := checkVelocity(res, "", linEst, angEst, linear.Y, angular.Z, tol); err != nil {
+		return fmt.Errorf(setVelocityErr, err)
 	}
-
-	// verify average speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(linear.Y, linEst, linearErr) || !rdkutils.Float64AlmostEqual(angular.Z, angEst, angularErr) {
-		return fmt.Errorf(setVelocityErr, fmt.Sprintf("measured velocity (linear: %v, angular: %v) did not equal requested velocity (linear: %v, angular: %v)", linEst, angEst, linear.Y, angular.Z))
+	if stepErr != nil {
+		return fmt.Errorf(setVelocityErr, stepErr)
 	}
 	return nil
 }
 
-func consecutiveVelocityTest(b base.Base, odometry movementsensor.MovementSensor, linear1, linear2 r3.Vector, des, data *os.File) error {
+func (r *Runner) consecutiveVelocityTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, linear1, linear2 r3.Vector, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	consecutiveVelErr := "error with consecutive SetVelocity calls, err = %v"
+	resp, stopRecording := recordResponse(des, data)
+	defer stopRecording()
+
 	// SetVelocity with linear1
-	if err := b.SetVelocity(context.Background(), linear1, r3.Vector{}, nil); err != nil {
+	if err := b.SetVelocity(ctx, linear1, r3.Vector{}, nil); err != nil {
 		return fmt.Errorf(consecutiveVelErr, err)
 	}
+	des.Write(samples.Record{Type: samples.SetVelocity, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear1.Y})
 
-	// let the base get up to speed for 5 seconds
-	time.Sleep(5 * time.Second)
+	// let the base get up to speed
+	if err := r.settle(ctx, odometry, nil, tol.settle, data, samples.SetVelocity); err != nil {
+		return fmt.Errorf(consecutiveVelErr, err)
+	}
 
 	// first goal velocity
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v\n", "sv", linear1.Y, 0.0, time.Since(startTime).Milliseconds()))
-	sampleCtx, cancel := context.WithCancel(context.Background())
-	linEst, angEst := sampleEverything(sampleCtx, odometry, nil, linear1.Y, 0.0, 5, data, "sv", cancel)
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v\n", "sv", linear1.Y, 0.0, time.Since(startTime).Milliseconds()))
+	des.Write(samples.Record{Type: samples.SetVelocity, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear1.Y})
+	sampleCtx, cancel := context.WithCancel(ctx)
+	linEst, angEst := r.sampleEverything(sampleCtx, odometry, nil, linear1.Y, 0.0, tol.sample.Seconds(), tol.estimator, data, samples.SetVelocity, cancel)
+	des.Write(samples.Record{Type: samples.SetVelocity, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear1.Y})
 
 	cancel()
-	// verify average speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(linear1.Y, linEst, linear1.Y*0.5) || !rdkutils.Float64AlmostEqual(0.0, angEst, 15.0) {
-		return fmt.Errorf(consecutiveVelErr, fmt.Sprintf("measured velocity (linear: %v, angular: %v) did not equal requested velocity (linear: %v, angular: %v)", linEst, angEst, linear1.Y, 0.0))
+	if err := checkVelocity(res, "first ", linEst, angEst, linear1.Y, 0.0, tol); err != nil {
+		checkSteps(res, resp.des, resp.data, velocityFields, tol, "first ")
+		return fmt.Errorf(consecutiveVelErr, err)
 	}
 
 	// SetVelocity with linear 2
-	if err := b.SetVelocity(context.Background(), linear2, r3.Vector{}, nil); err != nil {
+	if err := b.SetVelocity(ctx, linear2, r3.Vector{}, nil); err != nil {
 		return fmt.Errorf(consecutiveVelErr, err)
 	}
+	des.Write(samples.Record{Type: samples.SetVelocity, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear2.Y})
 
-	// let the base get up to speed for 5 seconds
-	time.Sleep(5 * time.Second)
+	// let the base get up to speed
+	if err := r.settle(ctx, odometry, nil, tol.settle, data, samples.SetVelocity); err != nil {
+		return fmt.Errorf(consecutiveVelErr, err)
+	}
 
 	// second goal velocity
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v\n", "sv", linear2.Y, 0.0, time.Since(startTime).Milliseconds()))
-	sampleCtx, cancel = context.WithCancel(context.Background())
-	linEst, angEst = sampleEverything(sampleCtx, odometry, nil, linear2.Y, 0.0, 10, data, "sv", cancel)
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v\n", "sv", linear2.Y, 0.0, time.Since(startTime).Milliseconds()))
+	des.Write(samples.Record{Type: samples.SetVelocity, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear2.Y})
+	sampleCtx, cancel = context.WithCancel(ctx)
+	linEst, angEst = r.sampleEverything(sampleCtx, odometry, nil, linear2.Y, 0.0, 2*tol.sample.Seconds(), tol.estimator, data, samples.SetVelocity, cancel)
+	des.Write(samples.Record{Type: samples.SetVelocity, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: linear2.Y})
 
 	cancel()
-	// verify average speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(linear2.Y, linEst, linear2.Y*0.5) || !rdkutils.Float64AlmostEqual(0.0, angEst, 15.0) {
-		return fmt.Errorf(consecutiveVelErr, fmt.Sprintf("measured velocity (linear: %v, angular: %v) did not equal requested velocity (linear: %v, angular: %v)", linEst, angEst, linear2.Y, 0.0))
+	stepErr := checkSteps(res, resp.des, resp.data, velocityFields, tol, "first ", "second ")
+	if err := checkVelocity(res, "second ", linEst, angEst, linear2.Y, 0.0, tol); err != nil {
+		return fmt.Errorf(consecutiveVelErr, err)
+	}
+	if stepErr != nil {
+		return fmt.Errorf(consecutiveVelErr, stepErr)
 	}
 
-	return b.Stop(context.Background(), nil)
+	return b.Stop(ctx, nil)
 }
 
-func moveStraightTest(b base.Base, odometry movementsensor.MovementSensor, distance, speed float64, des, data *os.File) error {
+func (r *Runner) moveStraightTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, distance, speed float64, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	moveStraightErr := fmt.Sprintf("error moving straight for %v mm at %v mm/sec", distance, speed)
 	moveStraightErr += ", err = %v"
-	odometry.DoCommand(context.Background(), map[string]interface{}{"reset": true})
+	odometry.DoCommand(ctx, map[string]interface{}{"reset": true})
 	dir := sign(distance * speed)
 
-	startPos, _, err := odometry.Position(context.Background(), posExtra)
+	startPos, _, err := odometry.Position(ctx, r.posExtra)
 	if err != nil {
 		return fmt.Errorf(moveStraightErr, err)
 	}
+	startOrientation, err := odometry.Orientation(ctx, nil)
+	if err != nil {
+		return fmt.Errorf(moveStraightErr, err)
+	}
+	start := poseFromOdometry(startPos, startOrientation)
+	check, err := r.startOdometryCheck(ctx, odometry, res, data, samples.MoveStraight)
+	if err != nil {
+		return fmt.Errorf(moveStraightErr, err)
+	}
+	defer check.stop()
 
-	data.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "ms", 0, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "ms", 0, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	data.Write(samples.Record{Type: samples.MoveStraight, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds()})
+	des.Write(samples.Record{Type: samples.MoveStraight, TimeMs: r.elapsed().Milliseconds()})
 
-	sampleCtx, cancel := context.WithCancel(context.Background())
+	sampleCtx, cancel := context.WithCancel(ctx)
 	done := make(chan bool)
 	var speedEst float64
 	go func() {
-		linEst, _ := sampleEverything(sampleCtx, odometry, nil, math.Abs(speed)*dir, 0.0, math.Abs(distance/speed), data, "ms", cancel)
+		defer r.safety.recoverPanic()
+		linEst, _ := r.sampleEverything(sampleCtx, odometry, nil, math.Abs(speed)*dir, 0.0, math.Abs(distance/speed), tol.estimator, data, samples.MoveStraight, cancel)
 		speedEst = linEst
 		done <- true
 	}()
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "ms", math.Abs(speed)*dir, 0.0, time.Since(startTime).Milliseconds(), 0, 0, 0))
-	err = b.MoveStraight(context.Background(), int(distance), speed, nil)
+	des.Write(samples.Record{Type: samples.MoveStraight, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: math.Abs(speed) * dir})
+	err = b.MoveStraight(ctx, int(distance), speed, nil)
 
 	// call cancel so sampleEverything returns
 	cancel()
@@ -682,102 +756,77 @@ func moveStraightTest(b base.Base, odometry movementsensor.MovementSensor, dista
 	if err != nil {
 		return fmt.Errorf(moveStraightErr, err)
 	}
+	odometryErr := check.finish(ctx, res)
 
-	endPos, _, err := odometry.Position(context.Background(), posExtra)
+	endPos, _, err := odometry.Position(ctx, r.posExtra)
 	if err != nil {
 		return fmt.Errorf(moveStraightErr, err)
 	}
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "ms", math.Abs(speed)*dir, 0.0, time.Since(startTime).Milliseconds(), startPos.Lat()+math.Abs(distance)*dir, startPos.Lng(), 0))
+	end := poseFromOdometry(endPos, nil)
 
-	totalDist := startPos.GreatCircleDistance(endPos) * 10.0
+	// record where the base ended so a replay judges the same end position
+	endX, endY, _ := end.record()
+	data.Write(samples.Record{Type: samples.MoveStraight, Marker: samples.End, TimeMs: r.elapsed().Milliseconds(), X: endX, Y: endY})
+	goalX, goalY, goalTheta := start.moved(math.Abs(distance) * dir).record()
+	des.Write(samples.Record{Type: samples.MoveStraight, TimeMs: r.elapsed().Milliseconds(), LinearVelocity: math.Abs(speed) * dir, X: goalX, Y: goalY, Theta: goalTheta})
 
-	// verify distance is approximately requested distance
-	if !rdkutils.Float64AlmostEqual(totalDist*dir, math.Abs(distance)*dir, math.Abs(distance*0.3)) {
-		return fmt.Errorf(moveStraightErr, fmt.Sprintf("measured distance %v did not equal requested distance %v", totalDist*dir, math.Abs(distance)*dir))
+	if err := checkMoveStraight(res, start.progressTo(end), speedEst, distance, speed, tol); err != nil {
+		return fmt.Errorf(moveStraightErr, err)
 	}
-
-	// verify speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(speedEst, math.Abs(speed)*dir, math.Abs(speed*0.5)) {
-		return fmt.Errorf(moveStraightErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", speedEst, math.Abs(speed)*dir))
+	if odometryErr != nil {
+		return fmt.Errorf(moveStraightErr, odometryErr)
 	}
-
 	return nil
 }
 
-func spinTest(b base.Base, odometry movementsensor.MovementSensor, distance, speed float64, testSpeed bool, des, data *os.File) error {
+func (r *Runner) spinTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, distance, speed float64, testSpeed bool, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	spinErr := fmt.Sprintf("error spinning for %v deg at %v deg/sec", distance, speed)
 	spinErr += ", err = %v"
-	odometry.DoCommand(context.Background(), map[string]interface{}{"reset": true})
+	odometry.DoCommand(ctx, map[string]interface{}{"reset": true})
 	time.Sleep(100 * time.Millisecond)
 	dir := sign(distance * speed)
 
-	data.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "s", 0.0, 0.0, time.Since(startTime).Milliseconds(), 0.0, 0.0, 0))
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "s", 0.0, math.Abs(speed)*dir, time.Since(startTime).Milliseconds(), 0, 0, 0))
-
-	var speedEst float64
-	sampleCtx, cancel := context.WithCancel(context.Background())
-	done := make(chan bool)
-	go func() {
-		_, angEst := sampleEverything(sampleCtx, odometry, nil, 0.0, math.Abs(speed)*dir, math.Abs(distance/speed), data, "s", cancel)
-		speedEst = angEst
-		done <- true
-	}()
-
-	start := time.Now()
-	err := b.Spin(context.Background(), distance, speed, nil)
-
-	// call cancel so sampleEverything returns
-	cancel()
-	// wait for sampleEverything to actually return so speedEst is respected
-	<-done
-
+	check, err := r.startOdometryCheck(ctx, odometry, res, data, samples.Spin)
 	if err != nil {
 		return fmt.Errorf(spinErr, err)
 	}
+	defer check.stop()
 
-	endTime := time.Now()
-	endPos, err := odometry.Orientation(context.Background(), nil)
+	start := time.Now()
+	turned, _, speedEst, err := r.trackSpin(ctx, b, odometry, distance, speed, tol.estimator, des, data, samples.Spin)
 	if err != nil {
 		return fmt.Errorf(spinErr, err)
 	}
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "s", 0.0, math.Abs(speed)*dir, time.Since(startTime).Milliseconds(), 0.0, 0.0, rdkutils.DegToRad(distance*dir)))
-
-	totalDist := distBetweenAngles(endPos.OrientationVectorRadians().Theta, 0, math.Abs(distance)*dir)
+	endTime := time.Now()
+	odometryErr := check.finish(ctx, res)
+	des.Write(samples.Record{Type: samples.Spin, TimeMs: r.elapsed().Milliseconds(), AngularVelocity: math.Abs(speed) * dir, Theta: rdkutils.DegToRad(distance * dir)})
 
-	// verify distance is approximately requested distance
-	if !rdkutils.Float64AlmostEqual(totalDist, math.Abs(distance)*dir, math.Abs(distance*0.3)) {
-		return fmt.Errorf(spinErr, fmt.Sprintf("measured distance %v did not equal requested distance %v", totalDist, math.Abs(distance)*dir))
+	if err := checkSpin(res, turned, speedEst, endTime.Sub(start), distance, speed, testSpeed, tol); err != nil {
+		return fmt.Errorf(spinErr, err)
 	}
-
-	if testSpeed {
-		// verify speed is approximately requested speed
-		if !rdkutils.Float64AlmostEqual(speedEst, math.Abs(speed)*dir, math.Abs(speed)*0.5) {
-			return fmt.Errorf(spinErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", speedEst, math.Abs(speed)*dir))
-		}
-	} else {
-		if float64(endTime.Sub(start))*1e-9 > 5 {
-			return fmt.Errorf(spinErr, fmt.Sprintf("spin call took longer than expected, actual time = %v, max allowed time = %v", float64(endTime.Sub(start))*1e-9, math.Abs(distance/speed*5)))
-		}
+	if odometryErr != nil {
+		return fmt.Errorf(spinErr, odometryErr)
 	}
-
 	return nil
 }
 
-func baseSetPowerTest(b base.Base, odometry movementsensor.MovementSensor, power float64) error {
+func (r *Runner) baseSetPowerTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, power float64, tol tolerance, res *testResult) error {
 	powerErr := "error setting power, err = %v"
 	// if power is negative, just test linear power
 	angPwr := 0.0
 	if power > 0 {
 		angPwr = 1 - power
 	}
-	if err := b.SetPower(context.Background(), r3.Vector{Y: power}, r3.Vector{Z: angPwr}, nil); err != nil {
+	if err := b.SetPower(ctx, r3.Vector{Y: power}, r3.Vector{Z: angPwr}, nil); err != nil {
 		return fmt.Errorf(powerErr, err)
 	}
 
 	// wait for base to start moving
-	time.Sleep(1 * time.Second)
-	powered, err := b.IsMoving(context.Background())
+	if !utils.SelectContextOrWait(ctx, tol.settle) {
+		return fmt.Errorf(powerErr, ctx.Err())
+	}
+	powered, err := b.IsMoving(ctx)
 	if err != nil {
 		return fmt.Errorf(powerErr, err)
 	}
@@ -786,11 +835,11 @@ func baseSetPowerTest(b base.Base, odometry movementsensor.MovementSensor, power
 		return fmt.Errorf(powerErr, fmt.Sprintf("base is not powered (linear = %v, angular = %v)", power, angPwr))
 	}
 
-	linVel, err := odometry.LinearVelocity(context.Background(), nil)
+	linVel, err := odometry.LinearVelocity(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(powerErr, err)
 	}
-	angVel, err := odometry.AngularVelocity(context.Background(), nil)
+	angVel, err := odometry.AngularVelocity(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(powerErr, err)
 	}
@@ -805,10 +854,10 @@ func baseSetPowerTest(b base.Base, odometry movementsensor.MovementSensor, power
 		}
 	}
 
-	if err := b.Stop(context.Background(), nil); err != nil {
+	if err := b.Stop(ctx, nil); err != nil {
 		return fmt.Errorf(powerErr, err)
 	}
-	powered, err = b.IsMoving(context.Background())
+	powered, err = b.IsMoving(ctx)
 	if err != nil {
 		return fmt.Errorf(powerErr, err)
 	}
@@ -820,28 +869,29 @@ func baseSetPowerTest(b base.Base, odometry movementsensor.MovementSensor, power
 	return nil
 }
 
-func goForTest(m motor.Motor, odometry movementsensor.MovementSensor, rpm, revolutions float64, des, data *os.File) error {
+func (r *Runner) goForTest(ctx context.Context, m motor.Motor, odometry movementsensor.MovementSensor, rpm, revolutions float64, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	goForErr := fmt.Sprintf("error going for %v rev at %v rpm", revolutions, rpm)
 	goForErr += ", err = %v"
 	dir := sign(rpm * revolutions)
 
-	startPos, err := m.Position(context.Background(), nil)
+	startPos, err := m.Position(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(goForErr, err)
 	}
 
-	sampleCtx, cancel := context.WithCancel(context.Background())
+	sampleCtx, cancel := context.WithCancel(ctx)
 	done := make(chan bool)
 	var rpmEst float64
 	go func() {
-		linEst, _ := sampleEverything(sampleCtx, odometry, &m, math.Abs(rpm)*dir, 0.0, math.Abs(revolutions/rpm*60), data, "gf", cancel)
+		defer r.safety.recoverPanic()
+		linEst, _ := r.sampleEverything(sampleCtx, odometry, &m, math.Abs(rpm)*dir, 0.0, math.Abs(revolutions/rpm*60), tol.estimator, data, samples.GoFor, cancel)
 		rpmEst = linEst
 		done <- true
 	}()
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gf", 0, 0, time.Since(startTime).Milliseconds(), startPos, 0, 0))
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gf", math.Abs(rpm)*dir, 0, time.Since(startTime).Milliseconds(), startPos, 0, 0))
-	err = m.GoFor(context.Background(), rpm, revolutions, nil)
+	des.Write(samples.Record{Type: samples.GoFor, TimeMs: r.elapsed().Milliseconds(), Position: startPos})
+	des.Write(samples.Record{Type: samples.GoFor, TimeMs: r.elapsed().Milliseconds(), RPM: math.Abs(rpm) * dir, Position: startPos})
+	err = m.GoFor(ctx, rpm, revolutions, nil)
 
 	// call cancel so sampleEverything returns
 	cancel()
@@ -852,58 +902,56 @@ func goForTest(m motor.Motor, odometry movementsensor.MovementSensor, rpm, revol
 		return fmt.Errorf(goForErr, err)
 	}
 
-	endPos, err := m.Position(context.Background(), nil)
+	endPos, err := m.Position(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(goForErr, err)
 	}
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gf", math.Abs(rpm)*dir, 0, time.Since(startTime).Milliseconds(), startPos+(math.Abs(revolutions)*dir), 0, 0))
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gf", 0, 0, time.Since(startTime).Milliseconds(), startPos+(math.Abs(revolutions)*dir), 0, 0))
-
-	totalDist := endPos - startPos
-	// verify distance is approximately requested distance
-	if !rdkutils.Float64AlmostEqual(totalDist, math.Abs(revolutions)*dir, math.Abs(revolutions*0.3)) {
-		return fmt.Errorf(goForErr, fmt.Sprintf("measured revolutions %v did not equal requested revolutions %v", totalDist, revolutions))
-	}
+	// record where the motor ended so a replay judges the same end position
+	data.Write(samples.Record{Type: samples.GoFor, Marker: samples.End, TimeMs: r.elapsed().Milliseconds(), Position: endPos})
+	des.Write(samples.Record{Type: samples.GoFor, TimeMs: r.elapsed().Milliseconds(), RPM: math.Abs(rpm) * dir, Position: startPos + (math.Abs(revolutions) * dir)})
+	des.Write(samples.Record{Type: samples.GoFor, TimeMs: r.elapsed().Milliseconds(), Position: startPos + (math.Abs(revolutions) * dir)})
 
-	// verify speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(rpmEst, math.Abs(rpm)*dir, math.Abs(rpm*0.5)) {
-		return fmt.Errorf(goForErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", rpmEst, math.Abs(rpm)*dir))
+	if err := checkGoFor(res, endPos-startPos, rpmEst, rpm, revolutions, tol); err != nil {
+		return fmt.Errorf(goForErr, err)
 	}
 	return nil
 }
 
-func goToTest(m motor.Motor, odometry movementsensor.MovementSensor, rpm, position float64, des, data *os.File) error {
+func (r *Runner) goToTest(ctx context.Context, m motor.Motor, odometry movementsensor.MovementSensor, rpm, position float64, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	goToErr := fmt.Sprintf("error going to position %v at %v rpm", position, rpm)
 	goToErr += ", err = %v"
 	var rpmEst float64
 
-	if err := m.ResetZeroPosition(context.Background(), -2, nil); err != nil {
+	if err := m.ResetZeroPosition(ctx, goToZeroOffset, nil); err != nil {
 		return fmt.Errorf(goToErr, err)
 	}
 
 	// sleep for one interval of wheeled odometry polling so there isn't a large change in position over a short period of time
-	time.Sleep(500 * time.Millisecond)
+	if !utils.SelectContextOrWait(ctx, tol.settle) {
+		return fmt.Errorf(goToErr, ctx.Err())
+	}
 
-	startPos, err := m.Position(context.Background(), nil)
+	startPos, err := m.Position(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(goToErr, err)
 	}
 
 	dir := sign((position - startPos) * rpm)
 
-	sampleCtx, cancel := context.WithCancel(context.Background())
+	sampleCtx, cancel := context.WithCancel(ctx)
 	done := make(chan bool)
 	go func() {
-		linEst, _ := sampleEverything(sampleCtx, odometry, &m, math.Abs(rpm)*dir, 0.0, math.Abs((position-startPos)/rpm*60), data, "gt", cancel)
+		defer r.safety.recoverPanic()
+		linEst, _ := r.sampleEverything(sampleCtx, odometry, &m, math.Abs(rpm)*dir, 0.0, math.Abs((position-startPos)/rpm*60), tol.estimator, data, samples.GoTo, cancel)
 		rpmEst = linEst
 		done <- true
 	}()
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gt", 0, 0, time.Since(startTime).Milliseconds(), startPos, 0, 0))
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gt", math.Abs(rpm)*dir, 0, time.Since(startTime).Milliseconds(), startPos, 0, 0))
+	des.Write(samples.Record{Type: samples.GoTo, TimeMs: r.elapsed().Milliseconds(), Position: startPos})
+	des.Write(samples.Record{Type: samples.GoTo, TimeMs: r.elapsed().Milliseconds(), RPM: math.Abs(rpm) * dir, Position: startPos})
 
-	err = m.GoTo(context.Background(), rpm, position, nil)
+	err = m.GoTo(ctx, rpm, position, nil)
 
 	// call cancel so sampleEverything returns
 	cancel()
@@ -914,120 +962,134 @@ func goToTest(m motor.Motor, odometry movementsensor.MovementSensor, rpm, positi
 		return fmt.Errorf(goToErr, err)
 	}
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "gt", math.Abs(rpm)*dir, 0, time.Since(startTime).Milliseconds(), position, 0, 0))
-
-	endPos, err := m.Position(context.Background(), nil)
+	endPos, err := m.Position(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(goToErr, err)
 	}
 
-	// verify start position is 2 after ResetZeroPosition
-	if startPos != 2 {
-		return fmt.Errorf(goToErr, fmt.Sprintf("startPos = %v when it should be 2", startPos))
-	}
-
-	// verify end position is approximately requested end position
-	if !rdkutils.Float64AlmostEqual(endPos, position, 1) {
-		return fmt.Errorf(goToErr, fmt.Sprintf("measured end position %v did not equal requested end position %v", endPos, position))
-	}
+	// record where the motor ended so a replay judges the same end position
+	data.Write(samples.Record{Type: samples.GoTo, Marker: samples.End, TimeMs: r.elapsed().Milliseconds(), Position: endPos})
+	des.Write(samples.Record{Type: samples.GoTo, TimeMs: r.elapsed().Milliseconds(), RPM: math.Abs(rpm) * dir, Position: position})
 
-	// verify speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(math.Abs(rpmEst), math.Abs(rpm), math.Abs(rpm*0.5)) {
-		return fmt.Errorf(goToErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", math.Abs(rpmEst), math.Abs(rpm)))
+	if err := checkGoTo(res, startPos, endPos, rpmEst, rpm, position, tol); err != nil {
+		return fmt.Errorf(goToErr, err)
 	}
 	return nil
 }
 
-func setRPMTest(m motor.Motor, odometry movementsensor.MovementSensor, rpm float64, des, data *os.File) error {
+func (r *Runner) setRPMTest(ctx context.Context, m motor.Motor, odometry movementsensor.MovementSensor, rpm float64, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	setRPMErr := fmt.Sprintf("error setting rpm at %v rpm", rpm)
 	setRPMErr += ", err = %v"
-	if err := m.SetRPM(context.Background(), rpm, nil); err != nil {
+	resp, stopRecording := recordResponse(des, data)
+	defer stopRecording()
+
+	if err := m.SetRPM(ctx, rpm, nil); err != nil {
 		return fmt.Errorf(setRPMErr, err)
 	}
+	des.Write(samples.Record{Type: samples.SetRPM, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), RPM: rpm})
 
 	// allow motor to get up to speed
-	time.Sleep(5 * time.Second)
+	if err := r.settle(ctx, odometry, &m, tol.settle, data, samples.SetRPM); err != nil {
+		return fmt.Errorf(setRPMErr, err)
+	}
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "rpm", rpm, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	des.Write(samples.Record{Type: samples.SetRPM, TimeMs: r.elapsed().Milliseconds(), RPM: rpm})
 
-	sampleCtx, cancel := context.WithCancel(context.Background())
-	rpmEst, _ := sampleEverything(sampleCtx, odometry, &m, rpm, 0.0, 5, data, "rpm", cancel)
+	sampleCtx, cancel := context.WithCancel(ctx)
+	rpmEst, _ := r.sampleEverything(sampleCtx, odometry, &m, rpm, 0.0, tol.sample.Seconds(), tol.estimator, data, samples.SetRPM, cancel)
 	cancel()
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "rpm", rpm, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	des.Write(samples.Record{Type: samples.SetRPM, TimeMs: r.elapsed().Milliseconds(), RPM: rpm})
 
-	if err := m.Stop(context.Background(), nil); err != nil {
+	if err := m.Stop(ctx, nil); err != nil {
 		return fmt.Errorf(setRPMErr, err)
 	}
 
-	// verify speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(rpmEst, rpm, math.Abs(rpm)*0.5) {
-		return fmt.Errorf(setRPMErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", rpmEst, rpm))
+	stepErr := checkSteps(res, resp.des, resp.data, rpmFields, tol)
+	if err := checkRPM(res, "", rpmEst, rpm, tol); err != nil {
+		return fmt.Errorf(setRPMErr, err)
+	}
+	if stepErr != nil {
+		return fmt.Errorf(setRPMErr, stepErr)
 	}
 	return nil
 }
 
-func consecutiveRPMTest(m motor.Motor, odometry movementsensor.MovementSensor, rpm1, rpm2 float64, des, data *os.File) error {
+func (r *Runner) consecutiveRPMTest(ctx context.Context, m motor.Motor, odometry movementsensor.MovementSensor, rpm1, rpm2 float64, tol tolerance, res *testResult, des, data *samples.Writer) error {
 	consecutiveRPMErr := "error with consecutive SetRPM calls, err = %v"
+	resp, stopRecording := recordResponse(des, data)
+	defer stopRecording()
+
 	// SetRPM with rpm1
-	if err := m.SetRPM(context.Background(), rpm1, nil); err != nil {
+	if err := m.SetRPM(ctx, rpm1, nil); err != nil {
 		return fmt.Errorf(consecutiveRPMErr, err)
 	}
+	des.Write(samples.Record{Type: samples.SetRPM, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), RPM: rpm1})
 
 	// allow motor to get up to speed
-	time.Sleep(5 * time.Second)
+	if err := r.settle(ctx, odometry, &m, tol.settle, data, samples.SetRPM); err != nil {
+		return fmt.Errorf(consecutiveRPMErr, err)
+	}
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "rpm", rpm1, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	des.Write(samples.Record{Type: samples.SetRPM, TimeMs: r.elapsed().Milliseconds(), RPM: rpm1})
 
-	sampleCtx, cancel := context.WithCancel(context.Background())
-	rpmEst, _ := sampleEverything(sampleCtx, odometry, &m, rpm1, 0.0, 5, data, "rpm", cancel)
+	sampleCtx, cancel := context.WithCancel(ctx)
+	rpmEst, _ := r.sampleEverything(sampleCtx, odometry, &m, rpm1, 0.0, tol.sample.Seconds(), tol.estimator, data, samples.SetRPM, cancel)
 	cancel()
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "rpm", rpm1, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	des.Write(samples.Record{Type: samples.SetRPM, TimeMs: r.elapsed().Milliseconds(), RPM: rpm1})
 
-	// verify speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(rpmEst, rpm1, math.Abs(rpm1)*0.5) {
-		return fmt.Errorf(consecutiveRPMErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", rpmEst, rpm1))
+	if err := checkRPM(res, "first ", rpmEst, rpm1, tol); err != nil {
+		checkSteps(res, resp.des, resp.data, rpmFields, tol, "first ")
+		return fmt.Errorf(consecutiveRPMErr, err)
 	}
 
 	// SetRPM with rpm2
-	if err := m.SetRPM(context.Background(), rpm2, nil); err != nil {
+	if err := m.SetRPM(ctx, rpm2, nil); err != nil {
 		return fmt.Errorf(consecutiveRPMErr, err)
 	}
+	des.Write(samples.Record{Type: samples.SetRPM, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), RPM: rpm2})
 
 	// allow motor to get up to speed
-	time.Sleep(5 * time.Second)
+	if err := r.settle(ctx, odometry, &m, tol.settle, data, samples.SetRPM); err != nil {
+		return fmt.Errorf(consecutiveRPMErr, err)
+	}
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "rpm", rpm2, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	des.Write(samples.Record{Type: samples.SetRPM, TimeMs: r.elapsed().Milliseconds(), RPM: rpm2})
 
-	sampleCtx, cancel = context.WithCancel(context.Background())
-	rpmEst, _ = sampleEverything(sampleCtx, odometry, &m, rpm2, 0.0, 5, data, "rpm", cancel)
+	sampleCtx, cancel = context.WithCancel(ctx)
+	rpmEst, _ = r.sampleEverything(sampleCtx, odometry, &m, rpm2, 0.0, tol.sample.Seconds(), tol.estimator, data, samples.SetRPM, cancel)
 	cancel()
 
-	des.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", "rpm", rpm2, 0, time.Since(startTime).Milliseconds(), 0, 0, 0))
+	des.Write(samples.Record{Type: samples.SetRPM, TimeMs: r.elapsed().Milliseconds(), RPM: rpm2})
 
-	// verify speed is approximately requested speed
-	if !rdkutils.Float64AlmostEqual(rpmEst, rpm2, math.Abs(rpm2)*0.5) {
-		return fmt.Errorf(consecutiveRPMErr, fmt.Sprintf("measured speed %v did not equal requested speed %v", rpmEst, rpm2))
+	stepErr := checkSteps(res, resp.des, resp.data, rpmFields, tol, "first ", "second ")
+	if err := checkRPM(res, "second ", rpmEst, rpm2, tol); err != nil {
+		return fmt.Errorf(consecutiveRPMErr, err)
+	}
+	if stepErr != nil {
+		return fmt.Errorf(consecutiveRPMErr, stepErr)
 	}
 
-	return m.Stop(context.Background(), nil)
+	return m.Stop(ctx, nil)
 }
 
-func motorSetPowerTest(m motor.Motor, power float64) error {
+func (r *Runner) motorSetPowerTest(ctx context.Context, m motor.Motor, power float64, tol tolerance, res *testResult) error {
 	setPowerErr := "error setting power, err = %v"
-	startPos, err := m.Position(context.Background(), nil)
+	startPos, err := m.Position(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(setPowerErr, err)
 	}
 
-	if err := m.SetPower(context.Background(), power, nil); err != nil {
+	if err := m.SetPower(ctx, power, nil); err != nil {
 		return fmt.Errorf(setPowerErr, err)
 	}
 
 	// wait for motor to start moving
-	time.Sleep(2 * time.Second)
-	powered, powerPct, err := m.IsPowered(context.Background(), nil)
+	if !utils.SelectContextOrWait(ctx, tol.settle) {
+		return fmt.Errorf(setPowerErr, ctx.Err())
+	}
+	powered, powerPct, err := m.IsPowered(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(setPowerErr, err)
 	}
@@ -1036,19 +1098,19 @@ func motorSetPowerTest(m motor.Motor, power float64) error {
 		return fmt.Errorf(setPowerErr, fmt.Sprintf("motor is not powered (power = %v)", powerPct))
 	}
 
-	if !rdkutils.Float64AlmostEqual(powerPct, power, math.Abs(power)*0.3) {
+	if !res.check("power", powerPct, power, math.Abs(power)*tol.speed) {
 		return fmt.Errorf(setPowerErr, fmt.Sprintf("measured power %v does not match requested power %v", powerPct, power))
 	}
 
-	if err := m.Stop(context.Background(), nil); err != nil {
+	if err := m.Stop(ctx, nil); err != nil {
 		return fmt.Errorf(setPowerErr, err)
 	}
-	powered, err = m.IsMoving(context.Background())
+	powered, err = m.IsMoving(ctx)
 	if err != nil {
 		return fmt.Errorf(setPowerErr, err)
 	}
 
-	endPos, err := m.Position(context.Background(), nil)
+	endPos, err := m.Position(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(setPowerErr, err)
 	}
@@ -1064,15 +1126,16 @@ func motorSetPowerTest(m motor.Motor, power float64) error {
 	return nil
 }
 
-func doMoveStraight(odometry movementsensor.MovementSensor, b base.Base, desDist, desVel float64, data *os.File) error {
-	sampleCtx, cancel := context.WithCancel(context.Background())
+func (r *Runner) doMoveStraight(ctx context.Context, odometry movementsensor.MovementSensor, b base.Base, desDist, desVel float64, data *samples.Writer) error {
+	sampleCtx, cancel := context.WithCancel(ctx)
 	done := make(chan bool)
 	go func() {
-		_, _ = sampleEverything(sampleCtx, odometry, nil, desVel, 0.0, desDist/desVel, data, "grid", cancel)
+		defer r.safety.recoverPanic()
+		_, _ = r.sampleEverything(sampleCtx, odometry, nil, desVel, 0.0, desDist/desVel, "", data, samples.Grid, cancel)
 		done <- true
 	}()
 
-	err := b.MoveStraight(context.Background(), int(desDist), desVel, nil)
+	err := b.MoveStraight(ctx, int(desDist), desVel, nil)
 
 	// call cancel so sampleEverything returns
 	cancel()
@@ -1082,131 +1145,224 @@ func doMoveStraight(odometry movementsensor.MovementSensor, b base.Base, desDist
 	return err
 }
 
-func doSpin(b base.Base, lastAng, desAng, desAngVel float64) float64 {
-	lastAng += desAng
-	if lastAng > 360 {
-		lastAng -= 360
-	}
+// doArc drives the base along an arc during the grid, at the arc's speed and the angular velocity that keeps it
+// on its radius for as long as the arc should take.
+func (r *Runner) doArc(ctx context.Context, odometry movementsensor.MovementSensor, b base.Base, m gridMove, data *samples.Writer) error {
+	angVel := m.angularVelocity()
+	if err := b.SetVelocity(ctx, r3.Vector{Y: m.speed}, r3.Vector{Z: angVel}, nil); err != nil {
+		return err
+	}
+	d := secondsToDuration(m.length() / m.speed)
+	arcCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	r.sampleEverything(arcCtx, odometry, nil, m.speed, angVel, d.Seconds(), "", data, samples.Grid, cancel)
+	// sampling stops early on errors, the arc still gets all of d
+	<-arcCtx.Done()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return b.Stop(ctx, nil)
+}
 
-	err := b.Spin(context.Background(), desAng, desAngVel, nil)
+// doSpin spins the base during the grid and returns how far it turned in degrees and the heading it ended at
+// in radians.
+func (r *Runner) doSpin(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, desAng, desAngVel float64, data *samples.Writer) (float64, float64, error) {
+	turned, theta, _, err := r.trackSpin(ctx, b, odometry, desAng, desAngVel, "", nil, data, samples.Grid)
 	if err != nil {
-		logger.Error(err)
-		return lastAng
+		return turned, theta, err
 	}
-	time.Sleep(1 * time.Second)
-	return lastAng
+	utils.SelectContextOrWait(ctx, gridSpinPause)
+	return turned, theta, nil
 }
 
-func runGridTest(b base.Base, odometry movementsensor.MovementSensor, des, data *os.File) {
-	gridPath := []string{"long-straight", "left", "short-straight", "left", "long-straight", "right", "short-straight", "right", "long-straight", "left", "short-straight", "left", "long-straight"}
-	odometry.DoCommand(context.Background(), map[string]interface{}{"reset": true})
+// trackSpin spins the base while sampling the odometry, and returns the signed rotation in degrees tracked
+// from its heading before, during and after the spin, the heading it ended at in radians and the estimated spin
+// speed. The start and end headings are recorded as markers, the requested speed on des when it is given.
+func (r *Runner) trackSpin(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, distance, speed float64, est string, des, data *samples.Writer, testType samples.Type) (float64, float64, float64, error) {
+	var heading headingTracker
+	orientation, err := odometry.Orientation(ctx, nil)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	theta := orientation.OrientationVectorRadians().Theta
+	heading.add(theta)
+	data.Write(samples.Record{Type: testType, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), Theta: theta})
+	if des != nil {
+		des.Write(samples.Record{Type: testType, TimeMs: r.elapsed().Milliseconds(), AngularVelocity: math.Abs(speed) * sign(distance*speed)})
+	}
+
+	// every heading sampled during the spin is tracked
+	stopObserving := data.Observe(func(rec samples.Record) {
+		if rec.Marker == "" {
+			heading.add(rec.Theta)
+		}
+	})
+	defer stopObserving()
 
-	desVel := 100.0
-	desDist := 0.0
-	desAng := 0.0
-	desAngVel := 30.0
-	posLat, posLng := 0.0, 0.0
-	lastAng := 0.0
+	var speedEst float64
+	sampleCtx, cancel := context.WithCancel(ctx)
+	done := make(chan bool)
+	go func() {
+		defer r.safety.recoverPanic()
+		_, speedEst = r.sampleEverything(sampleCtx, odometry, nil, 0.0, math.Abs(speed)*sign(distance*speed), math.Abs(distance/speed), est, data, testType, cancel)
+		done <- true
+	}()
 
-	var lat, lng, desLat, desLng = []float64{}, []float64{}, []float64{}, []float64{}
+	err = b.Spin(ctx, distance, speed, nil)
 
-	startPos, _, err := odometry.Position(context.Background(), posExtra)
+	// call cancel so sampleEverything returns
+	cancel()
+	// wait for sampleEverything to actually return so speedEst and the heading are respected
+	<-done
 	if err != nil {
-		logger.Error(err)
-		return
+		return heading.turned(), theta, speedEst, err
 	}
 
-	lat = append(lat, startPos.Lat())
-	lng = append(lng, startPos.Lng())
-	desLat = append(desLat, startPos.Lat())
-	desLng = append(desLng, startPos.Lng())
+	orientation, err = odometry.Orientation(ctx, nil)
+	if err != nil {
+		return heading.turned(), theta, speedEst, err
+	}
+	theta = orientation.OrientationVectorRadians().Theta
+	heading.add(theta)
+	// record where the base ended so a replay judges the same end orientation
+	data.Write(samples.Record{Type: testType, Marker: samples.End, TimeMs: r.elapsed().Milliseconds(), Theta: theta})
+	return heading.turned(), theta, speedEst, nil
+}
 
-	for _, s := range gridPath {
-		switch s {
-		case "long-straight":
-			desDist = 1500.0
-			posLat, posLng = writeDesired(des, posLat, posLng, lastAng, desDist)
-			desLat = append(desLat, posLat/1000.0)
-			desLng = append(desLng, posLng/1000.0)
+// grid test default speeds and tolerances
+const (
+	gridVel       = 100.0 // mm/sec
+	gridAngVel    = 30.0  // deg/sec
+	gridSpinPause = 1 * time.Second
+	// gridRMSTolerance is the largest allowed rms distance between where the base ended each straight or arc and
+	// where it should have, in mm
+	gridRMSTolerance = 150.0
+	// largest allowed trajectory errors: distance from the planned line or arc, distance driven as a fraction of
+	// the planned distance, heading after a spin and distance from the planned end
+	gridCrossTrackTolerance = 100.0 // mm
+	gridDistanceTolerance   = 0.1
+	gridHeadingTolerance    = 10.0  // deg
+	gridClosureTolerance    = 300.0 // mm
+	// gridArcStep is the angle between the points a planned arc is drawn with, in deg
+	gridArcStep = 10.0
+)
 
-			if err := doMoveStraight(odometry, b, desDist, desVel, data); err != nil {
-				logger.Error(err)
-				return
-			}
+func (r *Runner) runGridTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, des, data *samples.Writer) {
+	r.safety.track(b)
+	res := newResult(suiteGrid, b.Name().ShortName(), "grid", nil)
+	res.Name = "grid pattern=" + r.plan.Grid.name()
+	res.Case = "grid"
+	des.SetCase(res.Case)
+	data.SetCase(res.Case)
+	moves, err := r.plan.Grid.moves()
+	if err == nil {
+		err = runWithTimeout(ctx, gridTimeout(moves), func(ctx context.Context) error {
+			return r.gridTest(ctx, b, odometry, moves, res, des, data)
+		})
+		if err != nil {
+			r.safety.stopAll("grid test returned an error")
+		}
+	}
+	res.finish(err)
+	r.results.add(res)
+}
 
-			endPos, _, err := odometry.Position(context.Background(), posExtra)
+func (r *Runner) gridTest(ctx context.Context, b base.Base, odometry movementsensor.MovementSensor, moves []gridMove, res *testResult, des, data *samples.Writer) error {
+	gridErr := "error running grid test, err = %v"
+	odometry.DoCommand(ctx, map[string]interface{}{"reset": true})
+
+	startPos, _, err := odometry.Position(ctx, r.posExtra)
+	if err != nil {
+		return fmt.Errorf(gridErr, err)
+	}
+	startOrientation, err := odometry.Orientation(ctx, nil)
+	if err != nil {
+		return fmt.Errorf(gridErr, err)
+	}
+	// where the base should be and where it was at the start and the end of every straight and arc
+	desired := poseFromOdometry(startPos, startOrientation)
+	desPoses, poses := []pose{desired}, []pose{desired}
+	// the start and the end of every straight and arc are marked so a replay can split the samples into moves
+	x, y, theta := desired.record()
+	data.Write(samples.Record{Type: samples.Grid, Marker: samples.Start, TimeMs: r.elapsed().Milliseconds(), X: x, Y: y, Theta: theta})
+	var legs []gridLeg
+	var turns []gridTurn
+	resp, stopRecording := recordResponse(des, data)
+	defer stopRecording()
+
+	for _, m := range moves {
+		switch m.op {
+		case gridStraight, gridArc:
+			leg := gridLeg{from: desired, start: poses[len(poses)-1]}
+			sampled := len(resp.data)
+			if m.op == gridStraight {
+				leg.to = desired.moved(m.distance)
+				r.writeDesired(des, leg.from, leg.to)
+				err = r.doMoveStraight(ctx, odometry, b, m.distance, m.speed, data)
+			} else {
+				leg.to, leg.radius, leg.angle = desired.arced(m.radius, m.angle), m.radius, m.angle
+				r.writeDesired(des, arcPoses(leg.from, m.radius, m.angle)...)
+				err = r.doArc(ctx, odometry, b, m, data)
+			}
 			if err != nil {
-				logger.Error(err)
-				return
+				return fmt.Errorf(gridErr, err)
 			}
-
-			lat = append(lat, endPos.Lat())
-			lng = append(lng, endPos.Lng())
-
-		case "short-straight":
-			desDist = 500.0
-			posLat, posLng = writeDesired(des, posLat, posLng, lastAng, desDist)
-			desLat = append(desLat, posLat/1000.0)
-			desLng = append(desLng, posLng/1000.0)
-
-			if err := doMoveStraight(odometry, b, desDist, desVel, data); err != nil {
-				logger.Error(err)
-				return
+			desired = leg.to
+			desPoses = append(desPoses, desired)
+			for _, rec := range resp.data[sampled:] {
+				if rec.Type == samples.Grid && rec.Marker == "" {
+					leg.samples = append(leg.samples, poseFromRecord(rec))
+				}
 			}
 
-			endPos, _, err := odometry.Position(context.Background(), posExtra)
+			endPos, _, err := odometry.Position(ctx, r.posExtra)
 			if err != nil {
-				logger.Error(err)
-				return
+				return fmt.Errorf(gridErr, err)
 			}
-
-			lat = append(lat, endPos.Lat())
-			lng = append(lng, endPos.Lng())
-		case "left":
-			desAng = 90
-			lastAng = doSpin(b, lastAng, desAng, desAngVel)
-
-		case "right":
-			desAng = -90
-			lastAng = doSpin(b, lastAng, desAng, desAngVel)
+			leg.end = poseFromOdometry(endPos, nil)
+			x, y, _ := leg.end.record()
+			data.Write(samples.Record{Type: samples.Grid, Marker: samples.End, TimeMs: r.elapsed().Milliseconds(), X: x, Y: y})
+			poses = append(poses, leg.end)
+			legs = append(legs, leg)
+
+		case gridSpin:
+			desired = desired.turned(m.angle)
+			turned, theta, err := r.doSpin(ctx, b, odometry, m.angle, m.speed, data)
+			if err != nil {
+				return fmt.Errorf(gridErr, err)
+			}
+			r.logger.Debugf("grid spin of %v deg turned %v deg", m.angle, turned)
+			turn := gridTurn{planned: desired.theta, end: poses[len(poses)-1]}
+			turn.end.theta = theta
+			turns = append(turns, turn)
 		}
 	}
 
-	numSamples := len(lat)
-	rmsNumSamples := len(desLat)
-	rmsErrorSum := 0.0
-	var diff float64
-	for i := 0; i < numSamples; i++ {
-		predictedPoint := geo.NewPoint(desLat[i]/1000.0, desLng[i])
-		actualPoint := geo.NewPoint(lat[i], lng[i])
-		// diff = (predicted - actual)^2
-		diff = math.Pow(actualPoint.GreatCircleDistance(predictedPoint), 2)
-		rmsErrorSum += diff
-	}
+	metrics := analyzeGrid(legs, turns, poses[len(poses)-1], desired)
+	r.writeGridMetrics(metrics)
 
-	rmsErr := math.Sqrt(rmsErrorSum / float64(rmsNumSamples))
+	if err := checkGridRun(res, metrics, poses, desPoses); err != nil {
+		return fmt.Errorf(gridErr, err)
+	}
+	return nil
+}
 
-	if rmsErr > 150 {
-		storeErr := fmt.Errorf("rms error %v is higher than the minimum allowed error %v", rmsErr, 150)
-		failedTests = append(failedTests, fmt.Sprintf("grid test: %v", storeErr))
+// arcPoses returns the poses along an arc from a pose, every gridArcStep degrees and at its end.
+func arcPoses(from pose, radius, deg float64) []pose {
+	n := int(math.Ceil(math.Abs(deg) / gridArcStep))
+	poses := make([]pose, 0, n+1)
+	for i := 0; i <= n; i++ {
+		poses = append(poses, from.arced(radius, deg*float64(i)/float64(n)))
 	}
+	return poses
 }
 
-func writeDesired(file *os.File, posLat, posLng, lastAng, desDist float64) (float64, float64) {
-	// write desired path
-	file.WriteString(fmt.Sprintf("%.3v,%.3v\n", posLat, posLng))
-	if lastAng == 0 || lastAng == 360 {
-		posLat += desDist
-	} else if lastAng == 180 {
-		posLat -= desDist
-	} else if lastAng == -90 {
-		posLng += desDist
-	} else if lastAng == 90 {
-		posLng -= desDist
-	}
-	file.WriteString(fmt.Sprintf("%.3v,%.3v\n", posLat, posLng))
-
-	return posLat, posLng
+// writeDesired records a desired grid segment through the given poses
+func (r *Runner) writeDesired(des *samples.Writer, poses ...pose) {
+	for _, p := range poses {
+		x, y, _ := p.record()
+		des.Write(samples.Record{Type: samples.Grid, TimeMs: r.elapsed().Milliseconds(), X: x, Y: y})
+	}
 }
 
 func sign(num float64) float64 {
@@ -1216,36 +1372,34 @@ func sign(num float64) float64 {
 	return 1.0
 }
 
-func distBetweenAngles(endRad, startRad, originalDist float64) float64 {
-	turns := int(originalDist) / 360 % 360
-	total := rdkutils.RadToDeg((math.Sin(endRad) - math.Sin(startRad))) + float64(turns)*360
-	return total
+// settle waits d for an actuator to reach a new goal, recording samples of its response while it does.
+func (r *Runner) settle(ctx context.Context, odometry movementsensor.MovementSensor, m *motor.Motor, d time.Duration, data *samples.Writer, testType samples.Type) error {
+	settleCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	r.sampleEverything(settleCtx, odometry, m, 0, 0, d.Seconds(), "", data, testType, cancel)
+	// sampling stops early on errors, the actuator still gets all of d
+	<-settleCtx.Done()
+	return ctx.Err()
 }
 
-func average(arr [5]float64) float64 {
-	sum := 0.
-	for _, val := range arr {
-		sum += val
+func (r *Runner) sampleEverything(ctx context.Context, odometry movementsensor.MovementSensor, m *motor.Motor, goalLinVel, goalAngVel, timeEst float64, est string, data *samples.Writer, testType samples.Type, cancel func()) (float64, float64) {
+	// motor goals are passed as the linear goal
+	estimates, err := newSpeedEstimates(est, goalLinVel, goalAngVel, goalLinVel)
+	if err != nil {
+		r.logger.Error(err)
+		return -1, -1
 	}
-	return sum / float64(len(arr))
-}
-
-func sampleEverything(ctx context.Context, odometry movementsensor.MovementSensor, m *motor.Motor, goalLinVel, goalAngVel, timeEst float64, data *os.File, testType string, cancel func()) (float64, float64) {
-	bestLin, bestAng := 0.0, 0.0
-	maxLin, maxAng := 0.0, 0.0
 	prevMotorPos := 0.0
 	start := time.Now()
 	if m != nil {
 		var err error
 		prevMotorPos, err = (*m).Position(ctx, nil)
 		if err != nil {
-			logger.Error(err)
+			r.logger.Error(err)
 			return -1, -1
 		}
 	}
 	prevTime := time.Now()
-	var avgRPM [5]float64
-	avgRPMIndex := 0
 
 	for {
 		if !utils.SelectContextOrWait(ctx, tickerDuration) {
@@ -1256,103 +1410,70 @@ func sampleEverything(ctx context.Context, odometry movementsensor.MovementSenso
 		}
 
 		// motor tests
-		if testType == "rpm" || testType == "gt" || testType == "gf" {
+		if testType == samples.SetRPM || testType == samples.GoTo || testType == samples.GoFor {
 			if m == nil {
-				logger.Error("provide a valid motor")
+				r.logger.Error("provide a valid motor")
 				return -1, -1
 			}
 			motorPos, err := (*m).Position(ctx, nil)
 			if err != nil {
-				if errors.Is(err, context.Canceled) {
+				if ctx.Err() != nil {
 					break
 				}
-				logger.Error(err)
+				r.logger.Error(err)
 				return -1, -1
 			}
 			currTime := time.Now()
-			avgRPM[avgRPMIndex%5] = (motorPos - prevMotorPos) / currTime.Sub(prevTime).Minutes()
-			motorRPM := average(avgRPM)
-			data.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", testType, avgRPM[avgRPMIndex%5], prevMotorPos, time.Since(startTime).Milliseconds(), motorPos, 0, 0))
+			rpm := (motorPos - prevMotorPos) / currTime.Sub(prevTime).Minutes()
+			rec := samples.Record{Type: testType, TimeMs: r.elapsed().Milliseconds(), RPM: rpm, Position: motorPos, PrevPosition: prevMotorPos}
+			data.Write(rec)
+			estimates.add(rec)
 			prevMotorPos = motorPos
 			prevTime = currTime
-			avgRPMIndex++
-
-			// calculate rpm error margins
-			rpmErr := 0.0
-			if goalLinVel != 0 {
-				rpmErr = goalLinVel * 0.5
-			}
-
-			// check if motorRPM within the error margin of the goalLinVel
-			if rdkutils.Float64AlmostEqual(goalLinVel, motorRPM, rpmErr) {
-				bestLin = motorRPM
-			}
-
-			// check if the current motorRPM greater than the current max rpm
-			if (goalLinVel < 0 && motorRPM < maxLin) || (goalLinVel > 0 && motorRPM > maxLin) {
-
-				maxLin = motorRPM
-			}
-
 		} else { // base tests
-			pos, _, err := odometry.Position(ctx, posExtra)
+			pos, _, err := odometry.Position(ctx, r.posExtra)
 			if err != nil {
-				if errors.Is(err, context.Canceled) {
+				if ctx.Err() != nil {
 					break
 				}
-				logger.Error(err)
+				r.logger.Error(err)
 				return -1, -1
 			}
 			linVel, err := odometry.LinearVelocity(ctx, nil)
 			if err != nil {
-				if errors.Is(err, context.Canceled) {
+				if ctx.Err() != nil {
 					break
 				}
-				logger.Error(err)
+				r.logger.Error(err)
 				return -1, -1
 			}
 			angVel, err := odometry.AngularVelocity(ctx, nil)
 			if err != nil {
-				if errors.Is(err, context.Canceled) {
+				if ctx.Err() != nil {
 					break
 				}
-				logger.Error(err)
+				r.logger.Error(err)
 				return -1, -1
 			}
 			angle, err := odometry.Orientation(ctx, nil)
 			if err != nil {
-				if errors.Is(err, context.Canceled) {
+				if ctx.Err() != nil {
 					break
 				}
-				logger.Error(err)
+				r.logger.Error(err)
 				return -1, -1
 			}
-			data.WriteString(fmt.Sprintf("%v,%.3v,%.3v,%v,%.3v,%.3v,%.3v\n", testType, linVel.Y*1000, angVel.Z, time.Since(startTime).Milliseconds(), pos.Lat(), pos.Lng(), angle.OrientationVectorRadians().Theta))
-
-			// calculate linear and angular error margins
-			linErr, angErr := 50.0, 15.0
-			if goalLinVel != 0 {
-				linErr = math.Abs(goalLinVel) * 0.5
-			}
-			if goalAngVel != 0 {
-				angErr = math.Abs(goalAngVel) * 0.5
-			}
-
-			// check if linVel and angVel are within the error margin of the goalLinVel and goalAngVel, respectively
-			if rdkutils.Float64AlmostEqual(goalLinVel, linVel.Y*1000, linErr) {
-				bestLin = linVel.Y * 1000
-			}
-			if rdkutils.Float64AlmostEqual(goalAngVel, angVel.Z, angErr) {
-				bestAng = angVel.Z
-			}
-
-			// check if the current linVel and angVel are greater than the current max lin and ang vel
-			if (goalLinVel < 0 && linVel.Y*1000 < maxLin) || (goalLinVel > 0 && linVel.Y*1000 > maxLin) {
-				maxLin = linVel.Y * 1000
-			}
-			if (goalAngVel < 0 && angVel.Z < maxAng) || (goalAngVel > 0 && angVel.Z > maxAng) {
-				maxAng = angVel.Z
+			rec := samples.Record{
+				Type:            testType,
+				TimeMs:          r.elapsed().Milliseconds(),
+				LinearVelocity:  linVel.Y * 1000,
+				AngularVelocity: angVel.Z,
+				X:               pos.Lat(),
+				Y:               pos.Lng(),
+				Theta:           angle.OrientationVectorRadians().Theta,
 			}
+			data.Write(rec)
+			estimates.add(rec)
 		}
 
 		// check if the max time allowed has passed
@@ -1361,28 +1482,31 @@ func sampleEverything(ctx context.Context, odometry movementsensor.MovementSenso
 			break
 		}
 	}
-	if bestLin == 0 {
-		bestLin = maxLin
-	}
-	if bestAng == 0 {
-		bestAng = maxAng
+	if m != nil {
+		return estimates.rpm.estimate(), 0
 	}
-	return bestLin, bestAng
+	return estimates.linear.estimate(), estimates.angular.estimate()
 }
 
-func sendSlackMessage(msg string) {
+// sendSlackMessage posts msg to the slack webhook. A webhook that can't be reached or rejects the message is
+// an error, the run still writes its reports.
+func (r *Runner) sendSlackMessage(webhook, msg string) error {
 	data := []byte("{'text': '" + msg + "'}")
 	body := bytes.NewReader(data)
 
 	req, err := http.NewRequest("POST", webhook, body)
 	if err != nil {
-		logger.Error(err)
+		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		logger.Error(err)
+		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %v", resp.Status)
+	}
+	return nil
 }
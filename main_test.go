@@ -0,0 +1,1594 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/components/powersensor"
+	"go.viam.com/rdk/config"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/robot"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+	"go.viam.com/test"
+
+	"go.viam.com/utils"
+	"rovercanary/samples"
+)
+
+var errRPC = errors.New("rpc error: code = Unavailable")
+
+// scenarios every test function is run through. gain scales how far the fakes actually move compared to
+// what they were asked to do, err is returned by every fake call and canceled runs the test on a done context.
+var scenarios = []struct {
+	name     string
+	gain     float64
+	err      error
+	canceled bool
+	status   testStatus
+}{
+	{"pass", 1, nil, false, statusPass},
+	{"out of tolerance", 0.2, nil, false, statusFail},
+	{"rpc error", 1, errRPC, false, statusError},
+	{"canceled", 1, nil, true, statusError},
+}
+
+// newTestRunner returns a runner with no robot that logs to the test.
+func newTestRunner(t *testing.T) *Runner {
+	return newRunner(logging.NewTestLogger(t), nil, canaryConfig{}, testPlan{}, simulatedProfile, t.TempDir())
+}
+
+// testTolerance keeps settle and sampling short so the suite runs in seconds.
+func testTolerance() tolerance {
+	return tolerance{
+		speed:    0.3,
+		distance: 0.3,
+		settle:   50 * time.Millisecond,
+		sample:   500 * time.Millisecond,
+		// the first sample comes a ticker period after the command, later than the short settle
+		step: stepThresholds{RiseSec: 0.5, SettlingSec: 0.5},
+	}
+}
+
+// scriptedRover is a fake base and the odometry that tracks it. The odometry reports gain times whatever the
+// base was commanded to do. Calls the canary does not make panic on the nil embedded interfaces.
+type scriptedRover struct {
+	base.Base
+	mu       sync.Mutex
+	gain     float64
+	err      error
+	linear   float64   // mm/sec
+	angular  float64   // deg/sec
+	distance float64   // mm forward
+	theta    float64   // rad, at since
+	since    time.Time // when the angular velocity was last set
+}
+
+type scriptedOdometry struct {
+	movementsensor.MovementSensor
+	r *scriptedRover
+}
+
+func newScriptedRover(gain float64, err error) (*scriptedRover, *scriptedOdometry) {
+	r := &scriptedRover{gain: gain, err: err, since: time.Now()}
+	return r, &scriptedOdometry{r: r}
+}
+
+func (r *scriptedRover) Name() resource.Name {
+	return base.Named("base")
+}
+
+func (r *scriptedRover) set(linear, angular float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.theta, r.since = r.heading(), time.Now()
+	r.linear, r.angular = linear, angular
+	return nil
+}
+
+// heading is the rover's heading in rad, turning at gain times the commanded angular velocity.
+func (r *scriptedRover) heading() float64 {
+	return wrapAngle(r.theta + rdkutils.DegToRad(r.angular*r.gain*time.Since(r.since).Seconds()))
+}
+
+func (r *scriptedRover) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	return r.set(linear.Y, angular.Z)
+}
+
+func (r *scriptedRover) Stop(ctx context.Context, extra map[string]interface{}) error {
+	return r.set(0, 0)
+}
+
+func (r *scriptedRover) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	dist := float64(distanceMm)
+	if err := r.set(math.Abs(mmPerSec)*sign(dist*mmPerSec), 0); err != nil {
+		return err
+	}
+	if err := wait(ctx, dist/mmPerSec); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.distance += math.Abs(dist) * sign(dist*mmPerSec) * r.gain
+	r.mu.Unlock()
+	return r.set(0, 0)
+}
+
+func (r *scriptedRover) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	if err := r.set(0, math.Abs(degsPerSec)*sign(angleDeg*degsPerSec)); err != nil {
+		return err
+	}
+	if err := wait(ctx, angleDeg/degsPerSec); err != nil {
+		return err
+	}
+	return r.set(0, 0)
+}
+
+func (o *scriptedOdometry) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.distance, o.r.theta, o.r.since = 0, 0, time.Now()
+	return nil, nil
+}
+
+func (o *scriptedOdometry) Position(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	// relative positions are meters forward and to the right
+	return geo.NewPoint(o.r.distance/1000, 0), 0, o.r.err
+}
+
+func (o *scriptedOdometry) LinearVelocity(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	return r3.Vector{Y: o.r.linear * o.r.gain / 1000}, o.r.err
+}
+
+func (o *scriptedOdometry) AngularVelocity(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	return spatialmath.AngularVelocity{Z: o.r.angular * o.r.gain}, o.r.err
+}
+
+func (o *scriptedOdometry) Orientation(ctx context.Context, extra map[string]interface{}) (spatialmath.Orientation, error) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	return &spatialmath.OrientationVector{OZ: 1, Theta: o.r.heading()}, o.r.err
+}
+
+// scriptedEncoder counts scale times the ticks one wheel of a scripted rover turned, with the wheels of the
+// simulated profile.
+type scriptedEncoder struct {
+	encoder.Encoder
+	r     *scriptedRover
+	left  bool
+	scale float64
+}
+
+// newScriptedEncoders returns encoders on both wheels of a rover, counting leftScale and rightScale times their
+// ticks.
+func newScriptedEncoders(r *scriptedRover, leftScale, rightScale float64) wheelEncoders {
+	return wheelEncoders{
+		left:  &scriptedEncoder{r: r, left: true, scale: leftScale},
+		right: &scriptedEncoder{r: r, scale: rightScale},
+	}
+}
+
+func (e *scriptedEncoder) Name() resource.Name {
+	if e.left {
+		return encoder.Named("left-enc")
+	}
+	return encoder.Named("right-enc")
+}
+
+func (e *scriptedEncoder) Position(ctx context.Context, positionType encoder.PositionType, extra map[string]interface{}) (float64, encoder.PositionType, error) {
+	e.r.mu.Lock()
+	defer e.r.mu.Unlock()
+	p := simulatedProfile
+	// the right wheel drives forward and the left backward as the rover turns counterclockwise
+	wheel := e.r.distance + e.r.heading()*p.WheelBase/2
+	if e.left {
+		wheel = e.r.distance - e.r.heading()*p.WheelBase/2
+	}
+	return wheel / p.WheelCircumference * p.TicksPerRotation * e.scale, encoder.PositionTypeTicks, e.r.err
+}
+
+// scriptedMotor is a fake encoded motor whose position advances at gain times the commanded rpm.
+type scriptedMotor struct {
+	motor.Motor
+	mu    sync.Mutex
+	gain  float64
+	err   error
+	rpm   float64
+	power float64
+	pos   float64 // revolutions at since
+	since time.Time
+}
+
+const scriptedMaxRPM = 100
+
+func newScriptedMotor(gain float64, err error) *scriptedMotor {
+	return &scriptedMotor{gain: gain, err: err, since: time.Now()}
+}
+
+func (m *scriptedMotor) position() (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pos + m.rpm*m.gain*time.Since(m.since).Minutes(), m.err
+}
+
+func (m *scriptedMotor) set(rpm, power float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.pos += m.rpm * m.gain * time.Since(m.since).Minutes()
+	m.rpm, m.power, m.since = rpm, power, time.Now()
+	return nil
+}
+
+func (m *scriptedMotor) SetRPM(ctx context.Context, rpm float64, extra map[string]interface{}) error {
+	return m.set(rpm, 0)
+}
+
+func (m *scriptedMotor) SetPower(ctx context.Context, powerPct float64, extra map[string]interface{}) error {
+	return m.set(powerPct*scriptedMaxRPM, powerPct)
+}
+
+func (m *scriptedMotor) Stop(ctx context.Context, extra map[string]interface{}) error {
+	return m.set(0, 0)
+}
+
+func (m *scriptedMotor) GoFor(ctx context.Context, rpm, revolutions float64, extra map[string]interface{}) error {
+	if err := m.set(math.Abs(rpm)*sign(rpm*revolutions), 0); err != nil {
+		return err
+	}
+	if err := wait(ctx, revolutions/rpm*60); err != nil {
+		return err
+	}
+	return m.set(0, 0)
+}
+
+func (m *scriptedMotor) GoTo(ctx context.Context, rpm, position float64, extra map[string]interface{}) error {
+	start, err := m.position()
+	if err != nil {
+		return err
+	}
+	if err := m.set(math.Abs(rpm)*sign(position-start), 0); err != nil {
+		return err
+	}
+	if err := wait(ctx, (position-start)/rpm*60); err != nil {
+		return err
+	}
+	return m.set(0, 0)
+}
+
+func (m *scriptedMotor) ResetZeroPosition(ctx context.Context, offset float64, extra map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.pos, m.since = -offset, time.Now()
+	return nil
+}
+
+func (m *scriptedMotor) Position(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	return m.position()
+}
+
+func (m *scriptedMotor) IsPowered(ctx context.Context, extra map[string]interface{}) (bool, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.power != 0, m.power * m.gain, m.err
+}
+
+func (m *scriptedMotor) IsMoving(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rpm != 0, m.err
+}
+
+// wait blocks for the absolute number of seconds a motion takes, or until ctx is done.
+func wait(ctx context.Context, sec float64) error {
+	if !utils.SelectContextOrWait(ctx, secondsToDuration(sec)) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// newSampleWriter returns a writer of sample records to w.
+func newSampleWriter(t *testing.T, w io.Writer, kind samples.Kind) *samples.Writer {
+	t.Helper()
+	sw, err := samples.NewWriter(w, "test", "test", kind)
+	test.That(t, err, test.ShouldBeNil)
+	return sw
+}
+
+// runScenarios runs the test once per scenario and checks the status of its result.
+func runScenarios(t *testing.T, run func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error) {
+	t.Helper()
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if sc.canceled {
+				cancel()
+			}
+
+			des, data := newSampleWriter(t, io.Discard, samples.Desired), newSampleWriter(t, io.Discard, samples.Measured)
+			res := newResult("test", "fake", t.Name(), nil)
+			err := run(t, ctx, sc.gain, sc.err, res, des, data)
+			res.finish(err)
+
+			checkScenario(t, res, err, sc.err, sc.canceled, sc.status)
+		})
+	}
+}
+
+func checkScenario(t *testing.T, res *testResult, err, rpcErr error, canceled bool, status testStatus) {
+	t.Helper()
+	test.That(t, res.Status, test.ShouldEqual, status)
+	switch {
+	case status == statusPass:
+		test.That(t, err, test.ShouldBeNil)
+	case rpcErr != nil:
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, rpcErr.Error())
+	case canceled:
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, context.Canceled.Error())
+	default:
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, res.Measurements, test.ShouldNotBeEmpty)
+	}
+}
+
+func TestSetVelocity(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		b, odometry := newScriptedRover(gain, err)
+		return newTestRunner(t).setVelocityTest(ctx, b, odometry, r3.Vector{Y: 100}, r3.Vector{Z: 30}, testTolerance(), res, des, data)
+	})
+}
+
+func TestMoveStraight(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		b, odometry := newScriptedRover(gain, err)
+		r := newTestRunner(t)
+		r.encoders = newScriptedEncoders(b, 1, 1)
+		return r.moveStraightTest(ctx, b, odometry, -200, 400, testTolerance(), res, des, data)
+	})
+}
+
+func TestSpin(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		b, odometry := newScriptedRover(gain, err)
+		r := newTestRunner(t)
+		r.encoders = newScriptedEncoders(b, 1, 1)
+		return r.spinTest(ctx, b, odometry, 40, 80, true, testTolerance(), res, des, data)
+	})
+}
+
+func TestOdometryCheck(t *testing.T) {
+	// a quarter turn counterclockwise in place and a 381 mm straight, one wheel turn, in ticks
+	p := simulatedProfile
+	quarter := math.Pi / 2 * p.WheelBase / 2 / p.WheelCircumference * p.TicksPerRotation
+	distance, heading := p.wheelMotion(-quarter, quarter)
+	test.That(t, distance, test.ShouldAlmostEqual, 0)
+	test.That(t, heading, test.ShouldAlmostEqual, 90)
+	distance, heading = p.wheelMotion(p.TicksPerRotation, p.TicksPerRotation)
+	test.That(t, distance, test.ShouldAlmostEqual, 381)
+	test.That(t, heading, test.ShouldAlmostEqual, 0)
+
+	for _, tc := range []struct {
+		name              string
+		spin              bool
+		left, right       float64
+		measurements, err string
+	}{
+		{"straight", false, 1, 1, "odometry distance divergence from encoders (mm)", ""},
+		{"straight with miscounting encoders", false, 0.5, 0.5, "odometry distance divergence from encoders (mm)", "odometry distance diverged"},
+		{"spin", true, 1, 1, "odometry heading divergence from encoders (deg)", ""},
+		{"spin with a stuck right encoder", true, 1, 0, "odometry heading divergence from encoders (deg)", "odometry distance diverged"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			b, odometry := newScriptedRover(1, nil)
+			r := newTestRunner(t)
+			r.encoders = newScriptedEncoders(b, tc.left, tc.right)
+			des, data := newSampleWriter(t, io.Discard, samples.Desired), newSampleWriter(t, io.Discard, samples.Measured)
+			res := newResult("test", "fake", tc.name, nil)
+			var err error
+			if tc.spin {
+				err = r.spinTest(context.Background(), b, odometry, 40, 80, true, testTolerance(), res, des, data)
+			} else {
+				err = r.moveStraightTest(context.Background(), b, odometry, -200, 400, testTolerance(), res, des, data)
+			}
+			var names []string
+			for _, m := range res.Measurements {
+				names = append(names, m.Name)
+			}
+			test.That(t, names, test.ShouldContain, tc.measurements)
+			if tc.err == "" {
+				test.That(t, err, test.ShouldBeNil)
+				return
+			}
+			test.That(t, err, test.ShouldNotBeNil)
+			test.That(t, err.Error(), test.ShouldContainSubstring, tc.err)
+		})
+	}
+
+	// without a wheel base only distances are checked
+	res := newResult("test", "fake", "no wheel base", nil)
+	test.That(t, checkOdometry(res, 10, 90, 100, 0, false), test.ShouldBeNil)
+	test.That(t, res.Measurements, test.ShouldHaveLength, 1)
+}
+
+func TestGoFor(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		m := newScriptedMotor(gain, err)
+		return newTestRunner(t).goForTest(ctx, m, nil, 600, 8, testTolerance(), res, des, data)
+	})
+}
+
+func TestGoTo(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		m := newScriptedMotor(gain, err)
+		return newTestRunner(t).goToTest(ctx, m, nil, 600, 10, testTolerance(), res, des, data)
+	})
+}
+
+func TestSetRPM(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		m := newScriptedMotor(gain, err)
+		return newTestRunner(t).setRPMTest(ctx, m, nil, -600, testTolerance(), res, des, data)
+	})
+}
+
+func TestMotorSetPower(t *testing.T) {
+	runScenarios(t, func(t *testing.T, ctx context.Context, gain float64, err error, res *testResult, des, data *samples.Writer) error {
+		m := newScriptedMotor(gain, err)
+		return newTestRunner(t).motorSetPowerTest(ctx, m, 0.5, testTolerance(), res)
+	})
+}
+
+func TestSamplesRecorded(t *testing.T) {
+	b, odometry := newScriptedRover(1, nil)
+	var desOut, dataOut strings.Builder
+	des, data := newSampleWriter(t, &desOut, samples.Desired), newSampleWriter(t, &dataOut, samples.Measured)
+	des.SetCase("01-set_velocity")
+	data.SetCase("01-set_velocity")
+	res := newResult("test", "fake", "set_velocity", nil)
+	err := newTestRunner(t).setVelocityTest(context.Background(), b, odometry, r3.Vector{Y: 100.25}, r3.Vector{}, testTolerance(), res, des, data)
+	test.That(t, err, test.ShouldBeNil)
+
+	header, records, err := samples.ReadAll(strings.NewReader(desOut.String()))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, header.Kind, test.ShouldEqual, samples.Desired)
+	test.That(t, records, test.ShouldHaveLength, 3)
+	test.That(t, records[0].Marker, test.ShouldEqual, samples.Start)
+	for _, rec := range records {
+		test.That(t, rec.Case, test.ShouldEqual, "01-set_velocity")
+		test.That(t, rec.Type, test.ShouldEqual, samples.SetVelocity)
+		test.That(t, rec.LinearVelocity, test.ShouldEqual, 100.25)
+	}
+	_, records, err = samples.ReadAll(strings.NewReader(dataOut.String()))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, records, test.ShouldNotBeEmpty)
+}
+
+func TestConcurrentRunners(t *testing.T) {
+	step := planStep{Op: opSetVelocity, Linear: 100, SettleSec: 0.05, SampleSec: 0.5, DelaySec: 0.01, StepResponse: testTolerance().step}
+	runners := []*Runner{newTestRunner(t), newTestRunner(t)}
+	gains := []float64{1, 0.2}
+
+	var wg sync.WaitGroup
+	for i, r := range runners {
+		wg.Add(1)
+		go func(r *Runner, gain float64) {
+			defer wg.Done()
+			b, odometry := newScriptedRover(gain, nil)
+			des, data, closeFiles := r.initializeFiles("wheeled", suiteWheeledBase)
+			defer closeFiles()
+			r.runBaseTests(context.Background(), suiteWheeledBase, b, odometry, []planStep{step}, des, data)
+		}(r, gains[i])
+	}
+	wg.Wait()
+
+	// each runner only sees its own rover
+	test.That(t, runners[0].results.summary(), test.ShouldResemble, resultSummary{Total: 1, Passed: 1})
+	test.That(t, runners[1].results.summary(), test.ShouldResemble, resultSummary{Total: 1, Failed: 1})
+}
+
+func TestPose(t *testing.T) {
+	// odometry 1 m forward and 0.5 m to the right, facing left
+	p := poseFromOdometry(geo.NewPoint(1, 0.5), &spatialmath.OrientationVector{OZ: 1, Theta: math.Pi / 2})
+	test.That(t, p.point.X, test.ShouldAlmostEqual, 1000)
+	test.That(t, p.point.Y, test.ShouldAlmostEqual, -500)
+	test.That(t, p.theta, test.ShouldAlmostEqual, math.Pi/2)
+	x, y, theta := p.record()
+	test.That(t, x, test.ShouldAlmostEqual, 1)
+	test.That(t, y, test.ShouldAlmostEqual, 0.5)
+	test.That(t, poseFromRecord(samples.Record{X: x, Y: y, Theta: theta}), test.ShouldResemble, p)
+
+	// driving 200 mm facing left moves the base to the left
+	moved := p.moved(200)
+	test.That(t, moved.point.X, test.ShouldAlmostEqual, 1000)
+	test.That(t, moved.point.Y, test.ShouldAlmostEqual, -300)
+	test.That(t, p.distanceTo(moved), test.ShouldAlmostEqual, 200)
+	test.That(t, p.progressTo(moved), test.ShouldAlmostEqual, 200)
+	test.That(t, moved.progressTo(p), test.ShouldAlmostEqual, -200)
+	test.That(t, p.progressTo(p.moved(-50)), test.ShouldAlmostEqual, -50)
+
+	// headings that are not a multiple of 90 degrees
+	diagonal := pose{}.turned(45).moved(100)
+	test.That(t, diagonal.point.X, test.ShouldAlmostEqual, 100/math.Sqrt2)
+	test.That(t, diagonal.point.Y, test.ShouldAlmostEqual, 100/math.Sqrt2)
+	test.That(t, pose{}.turned(-90).turned(-135).theta, test.ShouldAlmostEqual, math.Pi*3/4)
+	test.That(t, pose{}.turned(180).moved(100).point.X, test.ShouldAlmostEqual, -100)
+
+	// facing left, a point further left is to the left of the line
+	test.That(t, p.crossTrackTo(pose{point: r3.Vector{X: 900, Y: 0}}), test.ShouldAlmostEqual, 100)
+	test.That(t, p.crossTrackTo(pose{point: r3.Vector{X: 1100, Y: 0}}), test.ShouldAlmostEqual, -100)
+}
+
+func TestGridMetrics(t *testing.T) {
+	// an L of 1000 mm forward, a left turn and 500 mm to the left; the base drifts 50 mm right on the first
+	// leg, drives it 30 mm short and turns 5 deg too far
+	first := gridLeg{from: pose{}, to: pose{}.moved(1000), start: pose{}}
+	first.samples = []pose{{point: r3.Vector{X: 500, Y: -20}}, {point: r3.Vector{X: 900, Y: -50}}}
+	first.end = pose{point: r3.Vector{X: 970, Y: -40}}
+	turn := gridTurn{planned: math.Pi / 2, end: pose{point: first.end.point, theta: rdkutils.DegToRad(95)}}
+	second := gridLeg{from: first.to.turned(90), to: first.to.turned(90).moved(500), start: first.end}
+	second.end = pose{point: r3.Vector{X: 930, Y: 460}}
+
+	m := analyzeGrid([]gridLeg{first, second}, []gridTurn{turn}, second.end, second.to)
+	test.That(t, m.Segments, test.ShouldHaveLength, 2)
+	test.That(t, m.Segments[0].MaxCrossTrackMm, test.ShouldAlmostEqual, 50)
+	test.That(t, m.Segments[0].RMSCrossTrackMm, test.ShouldAlmostEqual, math.Sqrt((20*20+50*50+40*40)/3.0))
+	test.That(t, m.Segments[0].DistanceErrorMm, test.ShouldAlmostEqual, -30)
+	test.That(t, m.Segments[1].MaxCrossTrackMm, test.ShouldAlmostEqual, 70)
+	test.That(t, m.Segments[1].DistanceErrorMm, test.ShouldAlmostEqual, 0)
+	test.That(t, m.Segments[1].To.X, test.ShouldAlmostEqual, 1)
+	test.That(t, m.Segments[1].To.Y, test.ShouldAlmostEqual, -0.5)
+	test.That(t, m.Spins, test.ShouldHaveLength, 1)
+	test.That(t, m.Spins[0].HeadingErrorDeg, test.ShouldAlmostEqual, 5)
+	test.That(t, m.ClosureMm, test.ShouldAlmostEqual, math.Hypot(70, 40))
+
+	res := newResult(suiteGrid, "sensor_base", "grid", nil)
+	test.That(t, checkGrid(res, m), test.ShouldBeNil)
+	test.That(t, res.Measurements, test.ShouldHaveLength, 6)
+	test.That(t, res.Measurements[0].Name, test.ShouldEqual, "segment 1 max cross-track error (mm)")
+	test.That(t, res.Measurements[4].Name, test.ShouldEqual, "spin 1 heading error (deg)")
+	test.That(t, res.Measurements[5].Name, test.ShouldEqual, "closure error (mm)")
+
+	// every metric is reported even after one fails
+	m.Spins[0].HeadingErrorDeg = -15
+	res = newResult(suiteGrid, "sensor_base", "grid", nil)
+	err := checkGrid(res, m)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "spin 1")
+	test.That(t, res.Measurements, test.ShouldHaveLength, 6)
+}
+
+func TestGridArcMetrics(t *testing.T) {
+	// a counterclockwise circle of 500 mm driven 20 mm wide, ending 10 deg short of closing it
+	circle := gridLeg{from: pose{}, to: pose{}.arced(500, 360), radius: 500, angle: 360, start: pose{}}
+	center := r3.Vector{Y: 500}
+	around := func(radius, deg float64) pose {
+		sin, cos := math.Sincos(rdkutils.DegToRad(deg))
+		return pose{point: center.Add(r3.Vector{X: radius * sin, Y: -radius * cos})}
+	}
+	for deg := 30.0; deg < 360; deg += 30 {
+		circle.samples = append(circle.samples, around(520, deg))
+	}
+	circle.end = around(500, 350)
+
+	m := analyzeGrid([]gridLeg{circle}, nil, circle.end, circle.to)
+	test.That(t, m.Segments, test.ShouldHaveLength, 1)
+	test.That(t, m.Segments[0].LengthMm, test.ShouldAlmostEqual, 1000*math.Pi)
+	test.That(t, m.Segments[0].MaxCrossTrackMm, test.ShouldAlmostEqual, 20)
+	test.That(t, m.Segments[0].DistanceErrorMm, test.ShouldAlmostEqual, -1000*math.Pi/36)
+	// the label goes halfway around, 1 m to the left of the start
+	test.That(t, m.Segments[0].Mid.X, test.ShouldAlmostEqual, 0)
+	test.That(t, m.Segments[0].Mid.Y, test.ShouldAlmostEqual, -1)
+
+	// a clockwise quarter arc ends to the right, facing right
+	quarter := pose{}.arced(500, -90)
+	test.That(t, quarter.point.X, test.ShouldAlmostEqual, 500)
+	test.That(t, quarter.point.Y, test.ShouldAlmostEqual, -500)
+	test.That(t, quarter.theta, test.ShouldAlmostEqual, -math.Pi/2)
+
+	// a straight driven backwards is measured against its planned direction
+	back := gridLeg{from: pose{}, to: pose{}.moved(-500), start: pose{}, end: pose{point: r3.Vector{X: -480}}}
+	m = analyzeGrid([]gridLeg{back}, nil, back.end, back.to)
+	test.That(t, m.Segments[0].DistanceErrorMm, test.ShouldAlmostEqual, -20)
+}
+
+func TestGridPath(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		path  string
+		moves []gridMove
+		err   string
+	}{
+		{"moves", "straight(1000, 150) spin(-90, 45); arc(500, 180, 80)", []gridMove{
+			{op: gridStraight, distance: 1000, speed: 150},
+			{op: gridSpin, angle: -90, speed: 45},
+			{op: gridArc, radius: 500, angle: 180, speed: 80},
+		}, ""},
+		{"default speeds", "straight(-200.5)\nspin(+45) // comment\narc(300, -90)", []gridMove{
+			{op: gridStraight, distance: -200.5, speed: gridVel},
+			{op: gridSpin, angle: 45, speed: gridAngVel},
+			{op: gridArc, radius: 300, angle: -90, speed: gridVel},
+		}, ""},
+		{"nested repeat", "repeat(2) { straight(100) repeat(2) { spin(90) } }", []gridMove{
+			{op: gridStraight, distance: 100, speed: gridVel},
+			{op: gridSpin, angle: 90, speed: gridAngVel},
+			{op: gridSpin, angle: 90, speed: gridAngVel},
+			{op: gridStraight, distance: 100, speed: gridVel},
+			{op: gridSpin, angle: 90, speed: gridAngVel},
+			{op: gridSpin, angle: 90, speed: gridAngVel},
+		}, ""},
+		{"empty", " // nothing", nil, "no moves"},
+		{"unknown move", "jump(10)", nil, "unknown move"},
+		{"zero distance", "straight(0, 100)", nil, "non-zero distance"},
+		{"negative speed", "spin(90, -30)", nil, "positive speed"},
+		{"too many arguments", "straight(1, 2, 3)", nil, "optional speed"},
+		{"zero radius", "arc(0, 90)", nil, "positive radius"},
+		{"missing parenthesis", "straight 100", nil, "expected ("},
+		{"missing comma", "straight(100 100)", nil, "expected , or )"},
+		{"fractional repeat", "repeat(1.5) { spin(90) }", nil, "whole count"},
+		{"repeat without a block", "repeat(2) spin(90)", nil, "{ block }"},
+		{"unclosed block", "repeat(2) { spin(90)", nil, "closing }"},
+		{"stray brace", "spin(90) }", nil, "unexpected }"},
+		{"too many moves", "repeat(100) { repeat(100) { spin(90) } }", nil, "more than"},
+		{"huge repeat", "repeat(1e18) { straight(100) }", nil, "more than"},
+		{"empty repeat", "repeat(1e18) { }", nil, "at least one move"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			moves, err := parseGridPath(tc.path)
+			if tc.err != "" {
+				test.That(t, err, test.ShouldNotBeNil)
+				test.That(t, err.Error(), test.ShouldContainSubstring, tc.err)
+				return
+			}
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, moves, test.ShouldResemble, tc.moves)
+		})
+	}
+
+	// every built-in pattern parses and ends where it started or, for the lawnmower, 1500 mm to the left
+	for name := range gridPatterns {
+		moves, err := gridPlan{Pattern: name}.moves()
+		test.That(t, err, test.ShouldBeNil)
+		end := pose{}
+		for _, m := range moves {
+			switch m.op {
+			case gridStraight:
+				end = end.moved(m.distance)
+			case gridSpin:
+				end = end.turned(m.angle)
+			case gridArc:
+				end = end.arced(m.radius, m.angle)
+			}
+		}
+		if name == defaultGridPattern {
+			test.That(t, end.point.X, test.ShouldAlmostEqual, 0)
+			test.That(t, end.point.Y, test.ShouldAlmostEqual, 1500)
+			continue
+		}
+		test.That(t, end.distanceTo(pose{}), test.ShouldAlmostEqual, 0)
+	}
+
+	// the lawnmower is the original grid route and keeps its timeout
+	moves, err := gridPlan{}.moves()
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, gridTimeout(moves), test.ShouldEqual, timeoutFactor*(75*time.Second+6*(3*time.Second+gridSpinPause))+testTimeoutMargin)
+	circle, err := gridPlan{Pattern: "circle"}.moves()
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, circle[0].angularVelocity(), test.ShouldAlmostEqual, rdkutils.RadToDeg(0.2))
+
+	_, err = gridPlan{Pattern: "spiral"}.moves()
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = gridPlan{Pattern: "square", Path: "spin(90)"}.moves()
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, testPlan{Grid: gridPlan{Path: "spin(0)"}}.validate(), test.ShouldNotBeNil)
+	plan, err := loadPlan("")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, plan.Grid.name(), test.ShouldEqual, defaultGridPattern)
+}
+
+func TestHeadingTracker(t *testing.T) {
+	// headings are given unwrapped in degrees and fed as the odometry reports them, in [0, 2pi) rad
+	for _, tc := range []struct {
+		name     string
+		headings []float64
+		turned   float64
+	}{
+		{"small spin", []float64{0, 20, 40}, 40},
+		{"spin past 90", []float64{0, 70, 140}, 140},
+		{"across zero", []float64{350, 360, 370}, 20},
+		{"clockwise", []float64{0, -30, -60, -90}, -90},
+		{"two turns", []float64{0, 100, 200, 300, 400, 500, 600, 700, 720}, 720},
+		{"clockwise past a turn", []float64{10, -80, -170, -260, -350, -390}, -400},
+		{"reversal", []float64{0, 45, 90, 45, 0, -45, -90}, -90},
+		{"reversal after a turn", []float64{0, 120, 240, 360, 300, 200}, 200},
+		{"no samples", nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var h headingTracker
+			for _, deg := range tc.headings {
+				h.add(math.Mod(rdkutils.DegToRad(deg)+4*math.Pi, 2*math.Pi))
+			}
+			test.That(t, h.turned(), test.ShouldAlmostEqual, tc.turned)
+		})
+	}
+}
+
+func TestSpinTurns(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		distance, speed float64
+	}{
+		{"more than a turn", 400, 400},
+		{"clockwise", 120, -240},
+		{"backwards clockwise", -120, 240},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, odometry := newScriptedRover(1, nil)
+			res := newResult("test", "fake", "spin", nil)
+			des, data := newSampleWriter(t, io.Discard, samples.Desired), newSampleWriter(t, io.Discard, samples.Measured)
+			err := newTestRunner(t).spinTest(context.Background(), b, odometry, tc.distance, tc.speed, true, testTolerance(), res, des, data)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, res.Measurements[0].Name, test.ShouldEqual, "distance")
+			test.That(t, res.Measurements[0].Measured, test.ShouldAlmostEqual, math.Abs(tc.distance)*sign(tc.distance*tc.speed), 10)
+		})
+	}
+
+	// a grid spin returns the heading it ended at, not the last sample it took
+	b, odometry := newScriptedRover(1, nil)
+	data := newSampleWriter(t, io.Discard, samples.Measured)
+	turned, theta, err := newTestRunner(t).doSpin(context.Background(), b, odometry, 90, 180, data)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, turned, test.ShouldAlmostEqual, 90, 10)
+	orientation, err := odometry.Orientation(context.Background(), nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, theta, test.ShouldEqual, orientation.OrientationVectorRadians().Theta)
+}
+
+func TestEstimators(t *testing.T) {
+	// a motor turning at 60 rpm sampled every 100 ms, with one interval that jittered into a 300 rpm reading
+	var window []samples.Record
+	for i := 0; i <= 10; i++ {
+		rec := samples.Record{TimeMs: int64(i * 100), Position: float64(i) / 10, RPM: 60}
+		if i == 5 {
+			rec.RPM = 300
+		}
+		window = append(window, rec)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		expected float64
+	}{
+		{estimatorMargin, 60},
+		{estimatorSlope, 60},
+		{estimatorTrimmedMean, 60},
+		{estimatorMedian, 60},
+	} {
+		est, err := estimateWindow(window, tc.name, 0, 0, 60)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, est.rpm.estimate(), test.ShouldAlmostEqual, tc.expected)
+	}
+
+	// the margin estimator takes the fastest sample when none was near the goal
+	window[10].RPM = 20
+	est, err := estimateWindow(window, estimatorMargin, 0, 0, 10)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, est.rpm.estimate(), test.ShouldEqual, 300)
+
+	// a base spinning at 90 deg/sec across the wrap of its heading
+	var spin []samples.Record
+	for i := 0; i <= 4; i++ {
+		theta := math.Mod(math.Pi*0.75+float64(i)*math.Pi/4, 2*math.Pi) - math.Pi
+		spin = append(spin, samples.Record{TimeMs: int64(i * 500), Theta: theta, AngularVelocity: 90})
+	}
+	est, err = estimateWindow(spin, estimatorSlope, 0, 90, 0)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, est.angular.estimate(), test.ShouldAlmostEqual, 90)
+
+	_, err = newEstimator("mode", 0, 0)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, planStep{Op: opSetRPM, RPM: 10, Estimator: "mode"}.validate([]string{opSetRPM}), test.ShouldNotBeNil)
+	test.That(t, planStep{Op: opSetRPM, RPM: 10}.tolerance().estimator, test.ShouldEqual, estimatorSlope)
+}
+
+func TestStepResponse(t *testing.T) {
+	// a 0 to 100 mm/s step commanded at 0 ms, overshooting to 115 before settling, sampled at steady state
+	// from 600 ms
+	var data []samples.Record
+	for i, v := range []float64{0, 20, 60, 95, 115, 105, 100, 104, 96, 100} {
+		data = append(data, samples.Record{TimeMs: int64(i * 100), LinearVelocity: v})
+	}
+	step := stepResponse{commandMs: 0, steadyMs: 600, endMs: 900, goal: 100, value: velocityFields[0].value}
+
+	m := step.analyze(data, 0.1)
+	test.That(t, m.reached, test.ShouldBeTrue)
+	test.That(t, m.rise, test.ShouldEqual, 200*time.Millisecond)
+	test.That(t, m.overshoot, test.ShouldAlmostEqual, 0.15)
+	test.That(t, m.settled, test.ShouldBeTrue)
+	test.That(t, m.settling, test.ShouldEqual, 500*time.Millisecond)
+	test.That(t, m.steadySamples, test.ShouldEqual, 4)
+	test.That(t, m.steadyStateErr, test.ShouldAlmostEqual, 0)
+	test.That(t, m.rmsErr, test.ShouldAlmostEqual, math.Sqrt(8))
+
+	thresholds := stepThresholds{RiseSec: 0.5, Overshoot: 0.1, SettlingSec: 1, SettlingBand: 0.1, SteadyStateError: 0.05, RMSError: 0.05}
+	res := newResult("test", "fake", "set_velocity", nil)
+	err := checkStep(res, "linear velocity", m, 100, thresholds)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "[overshoot]")
+	test.That(t, res.Measurements, test.ShouldHaveLength, 5)
+	test.That(t, res.Measurements[1].Name, test.ShouldEqual, "linear velocity overshoot")
+	test.That(t, res.Measurements[1].Passed, test.ShouldBeFalse)
+
+	// a response that never moved is missing its rise and settling times
+	still := make([]samples.Record, len(data))
+	for i, rec := range data {
+		still[i] = samples.Record{TimeMs: rec.TimeMs}
+	}
+	m = step.analyze(still, 0.1)
+	test.That(t, m.reached, test.ShouldBeFalse)
+	test.That(t, m.settled, test.ShouldBeFalse)
+	res = newResult("test", "fake", "set_velocity", nil)
+	err = checkStep(res, "linear velocity", m, 100, thresholds)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "rise time")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "settling time")
+}
+
+func TestCheckSteps(t *testing.T) {
+	// two steps to 100 mm/s, the second one does not change the goal and is not checked
+	des := []samples.Record{
+		{TimeMs: 0, Marker: samples.Start, LinearVelocity: 100},
+		{TimeMs: 300, LinearVelocity: 100},
+		{TimeMs: 600, LinearVelocity: 100},
+		{TimeMs: 600, Marker: samples.Start, LinearVelocity: 100},
+		{TimeMs: 900, LinearVelocity: 100},
+		{TimeMs: 1200, LinearVelocity: 100},
+	}
+	test.That(t, recordedSteps(des), test.ShouldHaveLength, 2)
+	test.That(t, recordedSteps(des[1:]), test.ShouldHaveLength, 1)
+
+	var data []samples.Record
+	for ms := int64(100); ms <= 1200; ms += 100 {
+		data = append(data, samples.Record{TimeMs: ms, LinearVelocity: 100})
+	}
+	res := newResult("test", "fake", "set_velocity", nil)
+	err := checkSteps(res, des, data, velocityFields, testTolerance(), "first ", "second ")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, res.Measurements, test.ShouldHaveLength, 5)
+	for _, m := range res.Measurements {
+		test.That(t, m.Name, test.ShouldStartWith, "first linear velocity ")
+		test.That(t, m.Passed, test.ShouldBeTrue)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	steps := []planStep{
+		{Op: opSetVelocity, Linear: 100},
+		{Op: opMoveStraight, Distance: -200, Speed: 400},
+		{Op: opGoFor, RPM: 600, Revolutions: 8},
+		{Op: opSetRPM, RPM: -600},
+		{Op: opSpin, Distance: 40, Speed: 80, TestSpeed: true},
+	}
+	for i := range steps {
+		steps[i].SpeedTolerance, steps[i].DistanceTolerance = 0.3, 0.3
+		steps[i].SettleSec, steps[i].SampleSec = 0.05, 0.5
+	}
+
+	for _, gain := range []float64{1, 0.2} {
+		gain := gain
+		t.Run(fmt.Sprintf("gain %v", gain), func(t *testing.T) {
+			t.Parallel()
+			r := newTestRunner(t)
+			b, odometry := newScriptedRover(gain, nil)
+			r.encoders = newScriptedEncoders(b, 1, 1)
+			m := newScriptedMotor(gain, nil)
+			var desOut, dataOut strings.Builder
+			des, data := newSampleWriter(t, &desOut, samples.Desired), newSampleWriter(t, &dataOut, samples.Measured)
+
+			var live []*testResult
+			for i, step := range steps {
+				res := newResult("test", "fake", step.Op, step.params())
+				des.SetCase(caseID(i, step.Op))
+				data.SetCase(caseID(i, step.Op))
+				var err error
+				if step.Op == opGoFor || step.Op == opSetRPM {
+					err = step.runMotor(context.Background(), r, m, odometry, res, des, data)
+				} else {
+					err = step.runBase(context.Background(), r, b, odometry, res, des, data)
+				}
+				res.finish(err)
+				live = append(live, res)
+			}
+
+			_, desRecords, err := samples.ReadAll(strings.NewReader(desOut.String()))
+			test.That(t, err, test.ShouldBeNil)
+			_, dataRecords, err := samples.ReadAll(strings.NewReader(dataOut.String()))
+			test.That(t, err, test.ShouldBeNil)
+			rec := newRecording(desRecords, dataRecords)
+			test.That(t, rec.des, test.ShouldHaveLength, len(steps))
+			for i, step := range steps {
+				res := newResult("test", "fake", step.Op, step.params())
+				replayStep(step, caseID(i, step.Op), rec, simulatedProfile, res)
+				test.That(t, res.Status, test.ShouldEqual, live[i].Status)
+				test.That(t, measurementNames(res), test.ShouldResemble, measurementNames(live[i]))
+			}
+
+			// a recording without encoder ticks is judged on the rest, noting the skipped odometry check
+			var noTicks []samples.Record
+			for _, r := range dataRecords {
+				if r.Marker != samples.Encoders {
+					noTicks = append(noTicks, r)
+				}
+			}
+			res := newResult("test", "fake", steps[1].Op, steps[1].params())
+			replayStep(steps[1], caseID(1, steps[1].Op), newRecording(desRecords, noTicks), simulatedProfile, res)
+			test.That(t, res.Status, test.ShouldEqual, live[1].Status)
+			test.That(t, res.SkippedChecks, test.ShouldResemble, []string{"odometry check skipped: no wheel encoder ticks recorded"})
+		})
+	}
+}
+
+func TestReplayGrid(t *testing.T) {
+	// the scripted rover drives along its starting heading, so the grid only comes back along it
+	plan := gridPlan{Path: "straight(200, 400) spin(360, 360) straight(-200, 400)"}
+	moves, err := plan.moves()
+	test.That(t, err, test.ShouldBeNil)
+
+	for gain, status := range map[float64]testStatus{1: statusPass, 0.2: statusFail} {
+		gain, status := gain, status
+		t.Run(fmt.Sprintf("gain %v", gain), func(t *testing.T) {
+			t.Parallel()
+			r := newTestRunner(t)
+			b, odometry := newScriptedRover(gain, nil)
+			var dataOut strings.Builder
+			des, data := newSampleWriter(t, io.Discard, samples.Desired), newSampleWriter(t, &dataOut, samples.Measured)
+			live := newResult(suiteGrid, "fake", "grid", nil)
+			live.finish(r.gridTest(context.Background(), b, odometry, moves, live, des, data))
+
+			header, records, err := samples.ReadAll(strings.NewReader(dataOut.String()))
+			test.That(t, err, test.ShouldBeNil)
+			res := newResult(suiteGrid, "fake", "grid", nil)
+			replayGrid(plan, header, records, res)
+			test.That(t, live.Status, test.ShouldEqual, status)
+			test.That(t, res.Status, test.ShouldEqual, live.Status)
+			test.That(t, measurementNames(res), test.ShouldResemble, measurementNames(live))
+			for i, m := range res.Measurements {
+				test.That(t, m.Measured, test.ShouldAlmostEqual, live.Measurements[i].Measured, 1e-6)
+			}
+
+			// a grid that stopped early or was recorded before it had markers is skipped
+			res = newResult(suiteGrid, "fake", "grid", nil)
+			replayGrid(plan, header, records[:len(records)-1], res)
+			test.That(t, res.Status, test.ShouldEqual, statusSkip)
+			test.That(t, res.Message, test.ShouldEqual, "incomplete recording for this test case")
+
+			header.Version = 2
+			res = newResult(suiteGrid, "fake", "grid", nil)
+			replayGrid(plan, header, records, res)
+			test.That(t, res.Status, test.ShouldEqual, statusSkip)
+			test.That(t, res.Message, test.ShouldContainSubstring, "schema version 2")
+		})
+	}
+}
+
+// measurementNames lists the names of a result's measurements in order.
+func measurementNames(res *testResult) []string {
+	var names []string
+	for _, m := range res.Measurements {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestRunDirManifest(t *testing.T) {
+	root := t.TempDir()
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	dir1, id1, err := createRunDir(root, start)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, id1, test.ShouldEqual, "20240301-123000")
+	dir2, id2, err := createRunDir(root, start)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, id2, test.ShouldEqual, "20240301-123000-002")
+	latest, err := latestRunDir(root)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, latest, test.ShouldEqual, dir2)
+
+	// the latest of more than ten runs started in the same second is still the last one created
+	var last, lastID string
+	for i := 3; i <= 12; i++ {
+		last, lastID, err = createRunDir(root, start)
+		test.That(t, err, test.ShouldBeNil)
+	}
+	test.That(t, lastID, test.ShouldEqual, "20240301-123000-012")
+	latest, err = latestRunDir(root)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, latest, test.ShouldEqual, last)
+	test.That(t, os.WriteFile(filepath.Join(root, "20240301-123000-009", machineConfigFile), []byte("{}"), 0o644), test.ShouldBeNil)
+	test.That(t, os.WriteFile(filepath.Join(root, "20240301-123000-010", machineConfigFile), []byte("{}"), 0o644), test.ShouldBeNil)
+	prev, _, err := previousRunWith(root, lastID)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, filepath.Base(prev), test.ShouldEqual, "20240301-123000-010")
+
+	cfg := canaryConfig{APIKey: "secret", Components: componentNames{WheeledBase: "viam_base"}}
+	r := newRunner(logging.NewTestLogger(t), nil, cfg, testPlan{Base: []planStep{{Op: opSetVelocity, Linear: 100}}}, simulatedProfile, dir1)
+	_, _, closeFiles := r.initializeFiles("wheeled", suiteWheeledBase)
+	closeFiles()
+
+	_, err = writeManifest(dir1, newManifest(r, r.start, true, true), "junit.xml")
+	test.That(t, err, test.ShouldBeNil)
+	m, err := readManifest(dir1)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, m.RunID, test.ShouldEqual, id1)
+	test.That(t, m.Config.APIKey, test.ShouldEqual, redacted)
+	test.That(t, m.Config.Components, test.ShouldResemble, cfg.Components)
+	test.That(t, m.Plan, test.ShouldResemble, r.plan)
+	test.That(t, m.Artifacts, test.ShouldResemble, []string{"wheeledData.jsonl", "wheeledDes.jsonl", "junit.xml"})
+}
+
+func TestEnvironment(t *testing.T) {
+	r := newRunner(logging.NewTestLogger(t), nil, canaryConfig{}, testPlan{}, simulatedProfile, t.TempDir())
+	env := r.collectEnvironment(context.Background())
+	test.That(t, env.ServerVersion, test.ShouldEqual, unknown)
+	test.That(t, env.Profile, test.ShouldEqual, simulatedProfile.Name)
+	test.That(t, env.OS, test.ShouldEqual, runtime.GOOS)
+
+	env.CanaryRevision, env.CanaryModified = "abc123", true
+	tags := env.tags()
+	test.That(t, tags, test.ShouldHaveLength, len(env.labels()))
+	test.That(t, tags, test.ShouldContain, "canary_revision:abc123-modified")
+	test.That(t, tags, test.ShouldContain, "server_version:unknown")
+}
+
+// stubMachine is a local robot with a fixed config whose resources are all ready, or whose status fails with err.
+type stubMachine struct {
+	robot.LocalRobot
+	cfg *config.Config
+	err error
+}
+
+func newStubMachine(names componentNames) *stubMachine {
+	cfg := &config.Config{}
+	for _, c := range []struct {
+		api  resource.API
+		name string
+	}{
+		{base.API, names.WheeledBase},
+		{base.API, names.SensorBase},
+		{motor.API, names.LeftMotor},
+		{motor.API, names.RightMotor},
+		{movementsensor.API, names.Odometry},
+	} {
+		cfg.Components = append(cfg.Components, resource.Config{Name: c.name, API: c.api, Model: fakeModel})
+	}
+	return &stubMachine{cfg: cfg}
+}
+
+func (m *stubMachine) MachineStatus(ctx context.Context) (robot.MachineStatus, error) {
+	if m.err != nil {
+		return robot.MachineStatus{}, m.err
+	}
+	status := robot.MachineStatus{Config: config.Revision{Revision: "rev1"}}
+	for _, c := range m.cfg.Components {
+		status.Resources = append(status.Resources, resource.Status{Name: c.ResourceName(), State: resource.NodeStateReady})
+	}
+	return status, nil
+}
+
+func (m *stubMachine) Config() *config.Config {
+	return m.cfg
+}
+
+func TestMachineConfigDiff(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	machine := newStubMachine(simulatedComponents)
+
+	root := t.TempDir()
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	dir1, _, err := createRunDir(root, start)
+	test.That(t, err, test.ShouldBeNil)
+	r1 := newRunner(logger, machine, canaryConfig{}, testPlan{}, simulatedProfile, dir1)
+	changes, prev := r1.recordMachineConfig(ctx)
+	test.That(t, changes, test.ShouldBeEmpty)
+	test.That(t, prev, test.ShouldBeEmpty)
+
+	// pretend the first run had different gains on the sensor base and an extra sensor
+	raw, err := os.ReadFile(filepath.Join(dir1, machineConfigFile))
+	test.That(t, err, test.ShouldBeNil)
+	var snap machineSnapshot
+	test.That(t, json.Unmarshal(raw, &snap), test.ShouldBeNil)
+	test.That(t, snap.Source, test.ShouldEqual, "local")
+	sensorBaseName := base.Named("sensor_base").String()
+	test.That(t, snap.Resources[sensorBaseName].Model, test.ShouldEqual, fakeModel.String())
+	sensorBase := snap.Resources[sensorBaseName]
+	sensorBase.Attributes = map[string]interface{}{"kp": 0.5}
+	snap.Resources[sensorBaseName] = sensorBase
+	snap.Resources[movementsensor.Named("gps").String()] = resourceSnapshot{Model: "rdk:builtin:gps-nmea"}
+	raw, err = json.Marshal(snap)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, os.WriteFile(filepath.Join(dir1, machineConfigFile), raw, 0o644), test.ShouldBeNil)
+
+	dir2, _, err := createRunDir(root, start.Add(time.Hour))
+	test.That(t, err, test.ShouldBeNil)
+	r2 := newRunner(logger, machine, canaryConfig{}, testPlan{}, simulatedProfile, dir2)
+	changes, prev = r2.recordMachineConfig(ctx)
+	test.That(t, prev, test.ShouldEqual, filepath.Base(dir1))
+	test.That(t, changes, test.ShouldResemble, []configChange{
+		{Path: "rdk:component:base/sensor_base.attributes.kp", Old: "0.5"},
+		{Path: "rdk:component:movement_sensor/gps.model", Old: `"rdk:builtin:gps-nmea"`},
+	})
+	diff, err := os.ReadFile(filepath.Join(dir2, configDiffFile))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(diff), test.ShouldContainSubstring, "- rdk:component:base/sensor_base.attributes.kp: 0.5")
+
+	// a machine whose status can not be read leaves an incomplete snapshot that is neither diffed nor diffed against
+	dir3, _, err := createRunDir(root, start.Add(2*time.Hour))
+	test.That(t, err, test.ShouldBeNil)
+	r3 := newRunner(logger, &stubMachine{cfg: machine.cfg, err: errRPC}, canaryConfig{}, testPlan{}, simulatedProfile, dir3)
+	changes, prev = r3.recordMachineConfig(ctx)
+	test.That(t, changes, test.ShouldBeEmpty)
+	test.That(t, prev, test.ShouldBeEmpty)
+	raw, err = os.ReadFile(filepath.Join(dir3, machineConfigFile))
+	test.That(t, err, test.ShouldBeNil)
+	snap = machineSnapshot{}
+	test.That(t, json.Unmarshal(raw, &snap), test.ShouldBeNil)
+	test.That(t, snap.Incomplete, test.ShouldBeTrue)
+	_, err = os.Stat(filepath.Join(dir3, configDiffFile))
+	test.That(t, err, test.ShouldNotBeNil)
+
+	dir4, _, err := createRunDir(root, start.Add(3*time.Hour))
+	test.That(t, err, test.ShouldBeNil)
+	r4 := newRunner(logger, machine, canaryConfig{}, testPlan{}, simulatedProfile, dir4)
+	changes, prev = r4.recordMachineConfig(ctx)
+	test.That(t, prev, test.ShouldEqual, filepath.Base(dir2))
+	test.That(t, changes, test.ShouldBeEmpty)
+}
+
+func TestPlotRun(t *testing.T) {
+	dir := t.TempDir()
+	r := newRunner(logging.NewTestLogger(t), nil, canaryConfig{Components: componentNames{SensorBase: "sensor_base"}}, testPlan{}, simulatedProfile, dir)
+
+	// the grid recorded desired samples but its measured file is missing
+	des, _, closeFiles := r.initializeFiles("grid", suiteGrid)
+	des.Write(samples.Record{Type: samples.Grid, X: 0.5})
+	closeFiles()
+	test.That(t, os.Remove(filepath.Join(dir, "gridData.jsonl")), test.ShouldBeNil)
+
+	images := r.plotRun()
+	test.That(t, images, test.ShouldBeEmpty)
+	results := r.results.all()
+	test.That(t, results, test.ShouldHaveLength, 1)
+	test.That(t, results[0].Suite, test.ShouldEqual, suiteGrid)
+	test.That(t, results[0].Component, test.ShouldEqual, "sensor_base")
+	test.That(t, results[0].Status, test.ShouldEqual, statusError)
+}
+
+func TestConfig(t *testing.T) {
+	// the environment of the test run must not leak into the configs below
+	for _, env := range []string{envAddress, envAPIKeyID, envAPIKey, envPartID, envWebhook, envProfile} {
+		t.Setenv(env, "")
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		test.That(t, os.WriteFile(path, []byte(content), 0o644), test.ShouldBeNil)
+		return path
+	}
+	valid := `{"address": "rover.viam.cloud", "api_key_id": "id", "api_key": "key", "part_id": "part", "webhook": "https://hooks.slack.com/x"}`
+
+	for _, tc := range []struct {
+		name string
+		file string
+		body string
+		env  map[string]string
+		err  []string
+	}{
+		{name: "json", file: "canary.json", body: valid},
+		{name: "yaml", file: "canary.yaml", body: "address: rover.viam.cloud\napi_key_id: id\napi_key: key\npart_id: part\nwebhook: https://hooks.slack.com/x\nhardware_profile: viam-rover-v2\n"},
+		{name: "yaml by the yml extension", file: "canary.yml", body: "address: a\napi_key_id: id\napi_key: key\npart_id: part\nwebhook: http://hook\n"},
+		{name: "json is not read as yaml", file: "canary.json", body: "address: a\n", err: []string{"error parsing config file"}},
+		{name: "missing fields", file: "canary.json", body: `{"address": "a"}`, err: []string{`"api_key_id" is required`, `"api_key" is required`, `"part_id" is required`, `"webhook" is required`}},
+		{name: "placeholders", file: "canary.json", body: `{"address": "<address>", "api_key_id": "id", "api_key": " <api key> ", "part_id": "part", "webhook": "https://hook"}`, err: []string{`"address" is still the placeholder`, `"api_key" is still the placeholder`}},
+		{name: "env overrides placeholders", file: "canary.json", body: `{"address": "<address>", "api_key_id": "id", "api_key": "<api key>", "part_id": "part", "webhook": "https://hook"}`, env: map[string]string{envAddress: "rover.viam.cloud", envAPIKey: "secret"}},
+		{name: "env only", env: map[string]string{envAddress: "a", envAPIKeyID: "id", envAPIKey: "key", envPartID: "part", envWebhook: "https://hook"}},
+		{name: "v1 profile", file: "canary.json", body: valid, env: map[string]string{envProfile: "viam-rover-v1"}},
+		{name: "simulated profile is not selectable", file: "canary.json", body: valid, env: map[string]string{envProfile: profileSimulated}, err: []string{`unknown hardware profile "simulated"`}},
+		{name: "env profile", file: "canary.json", body: valid, env: map[string]string{envProfile: "rover-v9"}, err: []string{`unknown hardware profile "rover-v9"`}},
+		{name: "webhook not a url", file: "canary.json", body: `{"address": "a", "api_key_id": "id", "api_key": "key", "part_id": "part", "webhook": "hooks.slack.com"}`, err: []string{"must be an http(s) url"}},
+		{name: "negative battery voltage", file: "canary.json", body: `{"address": "a", "api_key_id": "id", "api_key": "key", "part_id": "part", "webhook": "https://hook", "min_battery_voltage": -1}`, err: []string{"cannot be negative"}},
+		{name: "incomplete custom profile", file: "canary.json", body: `{"address": "a", "api_key_id": "id", "api_key": "key", "part_id": "part", "webhook": "https://hook", "hardware_profile": "custom", "custom_profile": {"ticks_per_rotation": 1992}}`, err: []string{"invalid custom hardware profile", "wheel_circumference_mm must be greater than zero"}},
+		{name: "missing file", file: "missing.json", err: []string{"error reading config file"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for env, val := range tc.env {
+				t.Setenv(env, val)
+			}
+			path := ""
+			if tc.file != "" {
+				path = filepath.Join(dir, tc.file)
+				if tc.body != "" {
+					path = write(tc.file, tc.body)
+				}
+			}
+			cfg, err := loadConfig(path)
+			if len(tc.err) == 0 {
+				test.That(t, err, test.ShouldBeNil)
+				test.That(t, cfg.Address, test.ShouldNotBeEmpty)
+				if secret, ok := tc.env[envAPIKey]; ok {
+					test.That(t, cfg.APIKey, test.ShouldEqual, secret)
+				}
+				if name, ok := tc.env[envProfile]; ok {
+					profile, err := cfg.hardwareProfile()
+					test.That(t, err, test.ShouldBeNil)
+					test.That(t, profile.Name, test.ShouldEqual, name)
+				}
+				return
+			}
+			test.That(t, err, test.ShouldNotBeNil)
+			for _, msg := range tc.err {
+				test.That(t, err.Error(), test.ShouldContainSubstring, msg)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name string
+		file string
+		body string
+		err  []string
+	}{
+		{name: "yaml", file: "plan.yaml", body: "base:\n  - op: move_straight\n    distance: 500\n    speed: 100\nmotor:\n  - op: set_rpm\n    rpm: 10\n"},
+		{name: "json", file: "plan.json", body: `{"base": [{"op": "spin", "distance": 90, "speed": 45}], "grid": {"pattern": "square"}}`},
+		{name: "yaml is not read as json", file: "plan.json", body: "base: []\n", err: []string{"error parsing plan file"}},
+		{name: "unknown operations", file: "plan.json", body: `{"base": [{"op": "set_rpm", "rpm": 10}], "motor": [{"op": "fly"}]}`, err: []string{"base step 0: unknown operation \"set_rpm\"", "motor step 0: unknown operation \"fly\""}},
+		{name: "missing parameters", file: "plan.yaml", body: "base:\n  - op: move_straight\n    speed: 100\nmotor:\n  - op: go_for\n    rpm: 10\n  - op: motor_set_power\n    power: 1.5\n", err: []string{"move_straight needs a non-zero distance and speed", "go_for needs a non-zero rpm and revolutions", "motor_set_power needs a power in [-1, 1]"}},
+		{name: "unknown grid pattern", file: "plan.yaml", body: "grid:\n  pattern: spiral\n", err: []string{`unknown grid pattern "spiral"`}},
+		{name: "missing file", file: "missing.yaml", err: []string{"error reading plan file"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.file)
+			if tc.body != "" {
+				test.That(t, os.WriteFile(path, []byte(tc.body), 0o644), test.ShouldBeNil)
+			}
+			_, err := loadPlan(path)
+			if len(tc.err) == 0 {
+				test.That(t, err, test.ShouldBeNil)
+				return
+			}
+			test.That(t, err, test.ShouldNotBeNil)
+			for _, msg := range tc.err {
+				test.That(t, err.Error(), test.ShouldContainSubstring, msg)
+			}
+		})
+	}
+
+	// the built-in plan is valid
+	plan, err := loadPlan("")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, plan.Base, test.ShouldNotBeEmpty)
+	test.That(t, plan.Motor, test.ShouldNotBeEmpty)
+}
+
+func TestReports(t *testing.T) {
+	results := &resultCollector{}
+	for _, tc := range []struct {
+		suite, component, op string
+		measured             float64
+		err                  error
+		skip                 string
+	}{
+		{suite: suiteWheeledBase, component: "viam_base", op: opSetVelocity, measured: 100},
+		{suite: suiteWheeledBase, component: "viam_base", op: opSpin, measured: 50, err: errors.New("spun too little")},
+		{suite: suiteEncodedMotor, component: "left", op: opGoFor, err: errRPC},
+		{suite: suiteEncodedMotor, component: "left", op: opGoTo, err: context.DeadlineExceeded},
+		{suite: suiteGrid, op: "suite", skip: "precondition failed"},
+	} {
+		res := newResult(tc.suite, tc.component, tc.op, nil)
+		if tc.skip != "" {
+			res.skip(tc.skip)
+		} else {
+			if tc.measured != 0 {
+				res.check("speed", tc.measured, 100, 10)
+			}
+			if tc.op == opSpin {
+				res.skipCheck(odometryCheckName, "no wheel encoders configured")
+			}
+			res.finish(tc.err)
+		}
+		results.add(res)
+	}
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	report := newRunReport(start, start.Add(time.Minute), simulatedProfile, runEnvironment{}, results)
+	test.That(t, report.Passed, test.ShouldBeFalse)
+	test.That(t, report.Summary, test.ShouldResemble, resultSummary{Total: 4, Passed: 1, Failed: 1, Errored: 1, TimedOut: 1, Skipped: 1})
+
+	// one testsuite per suite, in the order they ran
+	junit := report.junit()
+	test.That(t, junit.Tests, test.ShouldEqual, 5)
+	test.That(t, junit.Failures, test.ShouldEqual, 1)
+	test.That(t, junit.Errors, test.ShouldEqual, 2)
+	test.That(t, junit.Skipped, test.ShouldEqual, 1)
+	test.That(t, junit.Suites, test.ShouldHaveLength, 3)
+	test.That(t, junit.Suites[0].Name, test.ShouldEqual, suiteWheeledBase)
+	test.That(t, junit.Suites[0].Cases[1].Failure, test.ShouldNotBeNil)
+	test.That(t, junit.Suites[0].Cases[1].Failure.Body, test.ShouldContainSubstring, "speed: measured 50, expected 100 +/- 10 [FAIL]")
+	test.That(t, junit.Suites[0].Cases[1].SystemOut, test.ShouldContainSubstring, "odometry check skipped: no wheel encoders configured")
+	test.That(t, junit.Suites[1].Cases[1].Error.Type, test.ShouldEqual, string(statusTimeout))
+	test.That(t, junit.Suites[2].Cases[0].Classname, test.ShouldEqual, "rovercanary.grid")
+
+	// runs started in the same second keep their own reports
+	dir := filepath.Join(t.TempDir(), "reports")
+	junit1, json1, err := writeReports(dir, "20240301-123000", report)
+	test.That(t, err, test.ShouldBeNil)
+	junit2, json2, err := writeReports(dir, "20240301-123000-002", report)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, filepath.Base(junit1), test.ShouldEqual, "junit-20240301-123000.xml")
+	test.That(t, filepath.Base(json1), test.ShouldEqual, "summary-20240301-123000.json")
+	test.That(t, junit2, test.ShouldNotEqual, junit1)
+	test.That(t, json2, test.ShouldNotEqual, json1)
+
+	raw, err := os.ReadFile(json1)
+	test.That(t, err, test.ShouldBeNil)
+	var written runReport
+	test.That(t, json.Unmarshal(raw, &written), test.ShouldBeNil)
+	test.That(t, written.Passed, test.ShouldBeFalse)
+	test.That(t, written.Results, test.ShouldHaveLength, 5)
+	raw, err = os.ReadFile(junit1)
+	test.That(t, err, test.ShouldBeNil)
+	var writtenJUnit junitTestSuites
+	test.That(t, xml.Unmarshal(raw, &writtenJUnit), test.ShouldBeNil)
+	test.That(t, writtenJUnit.Tests, test.ShouldEqual, 5)
+
+	// a report directory that is a file can not be written to
+	_, _, err = writeReports(junit1, "20240301-123000", report)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestSlackMessage(t *testing.T) {
+	var received string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received = string(body)
+		if strings.Contains(received, "reject") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	r := newTestRunner(t)
+	test.That(t, r.sendSlackMessage(hook.URL, "2 tests failed"), test.ShouldBeNil)
+	test.That(t, received, test.ShouldContainSubstring, "2 tests failed")
+
+	err := r.sendSlackMessage(hook.URL, "reject")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "403")
+
+	// an unreachable webhook is an error rather than a panic that loses the run's reports
+	hook.Close()
+	test.That(t, r.sendSlackMessage(hook.URL, "2 tests failed"), test.ShouldNotBeNil)
+	test.That(t, r.sendSlackMessage("://not a url", "2 tests failed"), test.ShouldNotBeNil)
+}
+
+// stubActuator counts the stops it receives, failing them with err or blocking until the context is done.
+type stubActuator struct {
+	name    string
+	err     error
+	block   bool
+	mu      sync.Mutex
+	stopped int
+}
+
+func (a *stubActuator) Name() resource.Name {
+	return motor.Named(a.name)
+}
+
+func (a *stubActuator) Stop(ctx context.Context, extra map[string]interface{}) error {
+	a.mu.Lock()
+	a.stopped++
+	a.mu.Unlock()
+	if a.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return a.err
+}
+
+func (a *stubActuator) stops() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stopped
+}
+
+func TestSafetySupervisor(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	s := newSafetySupervisor(logger, 100*time.Millisecond)
+	test.That(t, s.stopAll("nothing tracked"), test.ShouldBeNil)
+
+	left, right := &stubActuator{name: "left"}, &stubActuator{name: "right", err: errRPC}
+	stuck := &stubActuator{name: "stuck", block: true}
+	s.track(left)
+	s.track(right)
+	s.track(stuck)
+	// tracking an actuator again does not stop it twice
+	s.track(left)
+
+	start := time.Now()
+	err := s.stopAll("test returned an error")
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, errRPC.Error())
+	test.That(t, err.Error(), test.ShouldContainSubstring, context.DeadlineExceeded.Error())
+	test.That(t, left.stops(), test.ShouldEqual, 1)
+	test.That(t, right.stops(), test.ShouldEqual, 1)
+	test.That(t, stuck.stops(), test.ShouldEqual, 1)
+
+	// a panic in any goroutine of the run stops every actuator and still crashes it
+	done := make(chan interface{})
+	go func() {
+		defer func() { done <- recover() }()
+		defer s.recoverPanic()
+		panic("sampler panicked")
+	}()
+	test.That(t, <-done, test.ShouldEqual, "sampler panicked")
+	test.That(t, left.stops(), test.ShouldEqual, 2)
+
+	// no panic, no stop
+	func() {
+		defer s.recoverPanic()
+	}()
+	test.That(t, left.stops(), test.ShouldEqual, 2)
+
+	test.That(t, signalExitCode(syscall.SIGINT), test.ShouldEqual, 130)
+	test.That(t, signalExitCode(syscall.SIGTERM), test.ShouldEqual, 143)
+	test.That(t, signalExitCode(os.Interrupt), test.ShouldEqual, 130)
+}
+
+// stubPowerSensor reads a fixed voltage, current and power, or fails every read with err.
+type stubPowerSensor struct {
+	powersensor.PowerSensor
+	volts float64
+	err   error
+}
+
+func (p *stubPowerSensor) Name() resource.Name {
+	return powersensor.Named("ina219")
+}
+
+func (p *stubPowerSensor) Voltage(ctx context.Context, extra map[string]interface{}) (float64, bool, error) {
+	return p.volts, false, p.err
+}
+
+func (p *stubPowerSensor) Current(ctx context.Context, extra map[string]interface{}) (float64, bool, error) {
+	return 2.2, false, p.err
+}
+
+func (p *stubPowerSensor) Power(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	return 9.8, p.err
+}
+
+// stubIMU reads a fixed z acceleration, or fails with err.
+type stubIMU struct {
+	movementsensor.MovementSensor
+	z   float64
+	err error
+}
+
+func (m *stubIMU) Name() resource.Name {
+	return movementsensor.Named("imu")
+}
+
+func (m *stubIMU) LinearAcceleration(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	return r3.Vector{Z: m.z}, m.err
+}
+
+func TestPreflight(t *testing.T) {
+	// the simulated profile's pack is 1.5 V +/- 0.1 and its imu reads 2 m/s^2
+	for _, tc := range []struct {
+		name       string
+		minVoltage float64
+		ps         *stubPowerSensor
+		imu        *stubIMU
+		results    int
+		err        []string
+	}{
+		{name: "healthy", ps: &stubPowerSensor{volts: 1.5}, imu: &stubIMU{z: 2}, results: 4},
+		{name: "not configured"},
+		{name: "low battery", ps: &stubPowerSensor{volts: 1.3}, imu: &stubIMU{z: 2}, results: 4, err: []string{"battery voltage 1.3 is below the minimum of 1.4"}},
+		{name: "configured minimum", minVoltage: 1.2, ps: &stubPowerSensor{volts: 1.3}, results: 3},
+		{name: "power sensor unreachable", ps: &stubPowerSensor{err: errRPC}, results: 3, err: []string{"battery_voltage", "battery_current", "battery_power", "power sensor is unreachable"}},
+		{name: "imu unreachable", imu: &stubIMU{err: errRPC}, results: 1, err: []string{"imu is unreachable"}},
+		{name: "imu does not read gravity", imu: &stubIMU{z: 0.1}, results: 1, err: []string{"imu does not read gravity"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newRunner(logging.NewTestLogger(t), nil, canaryConfig{MinBatteryVoltage: tc.minVoltage}, testPlan{}, simulatedProfile, t.TempDir())
+			var ps powersensor.PowerSensor
+			if tc.ps != nil {
+				ps = tc.ps
+			}
+			var imu movementsensor.MovementSensor
+			if tc.imu != nil {
+				imu = tc.imu
+			}
+			err := r.preflight(context.Background(), ps, imu)
+			test.That(t, r.results.all(), test.ShouldHaveLength, tc.results)
+			if len(tc.err) == 0 {
+				test.That(t, err, test.ShouldBeNil)
+				test.That(t, r.canMove(suiteSensorBase, err), test.ShouldBeTrue)
+				return
+			}
+			test.That(t, err, test.ShouldNotBeNil)
+			for _, msg := range tc.err {
+				test.That(t, err.Error(), test.ShouldContainSubstring, msg)
+			}
+
+			// a failed precondition is skipped with its reason and does not fail the run, but is still
+			// reported to slack
+			var skippedChecks int
+			for _, res := range r.results.all() {
+				if res.Status == statusSkip {
+					skippedChecks++
+					test.That(t, res.Message, test.ShouldStartWith, "precondition failed")
+				}
+			}
+			test.That(t, skippedChecks, test.ShouldBeGreaterThan, 0)
+			summary := r.results.summary()
+			test.That(t, summary.unsuccessful(), test.ShouldEqual, 0)
+			test.That(t, summary.Skipped, test.ShouldEqual, skippedChecks)
+			message := r.results.slackMessage(r.env)
+			test.That(t, message, test.ShouldStartWith, "motion tests skipped")
+			test.That(t, message, test.ShouldNotContainSubstring, "tests failed")
+			test.That(t, message, test.ShouldContainSubstring, tc.err[len(tc.err)-1])
+
+			// motion suites are skipped with the reason
+			test.That(t, r.canMove(suiteSensorBase, err), test.ShouldBeFalse)
+			results := r.results.all()
+			skipped := results[len(results)-1]
+			test.That(t, skipped.Suite, test.ShouldEqual, suiteSensorBase)
+			test.That(t, skipped.Status, test.ShouldEqual, statusSkip)
+			test.That(t, skipped.Message, test.ShouldStartWith, "precondition failed")
+			test.That(t, newRunReport(r.start, time.Now(), simulatedProfile, r.env, r.results).Passed, test.ShouldBeTrue)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	manifestFile = "manifest.json"
+	runIDFormat  = "20060102-150405"
+	redacted     = "redacted"
+)
+
+// runManifest describes one run directory: which run it was, where and how it ran and the files it produced.
+type runManifest struct {
+	RunID     string          `json:"run_id"`
+	Start     time.Time       `json:"start"`
+	End       time.Time       `json:"end"`
+	Host      string          `json:"host"`
+	Simulated bool            `json:"simulated"`
+	Passed    bool            `json:"passed"`
+	Config    canaryConfig    `json:"config"`
+	Plan      testPlan        `json:"plan"`
+	Profile   hardwareProfile `json:"hardware_profile"`
+	Env       runEnvironment  `json:"environment"`
+	// PreviousRun is the run the machine config was compared against, MachineConfigChanges what changed since.
+	PreviousRun          string         `json:"previous_run,omitempty"`
+	MachineConfigChanges []configChange `json:"machine_config_changes,omitempty"`
+	// Artifacts are the files the run produced, relative to the run directory when inside it.
+	Artifacts []string `json:"artifacts"`
+}
+
+// createRunDir creates a new directory for a run started at start under root and returns its path and run ID.
+// The ID is the start time, suffixed with a zero-padded count when another run already claimed it so that
+// IDs keep sorting chronologically.
+func createRunDir(root string, start time.Time) (string, string, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return "", "", err
+	}
+	base := start.Format(runIDFormat)
+	id := base
+	for i := 2; ; i++ {
+		dir := filepath.Join(root, id)
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return dir, id, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", "", err
+		}
+		id = fmt.Sprintf("%v-%03d", base, i)
+	}
+}
+
+// latestRunDir returns the most recent run directory under root.
+func latestRunDir(root string) (string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", err
+	}
+	// run IDs start with the start time, so they sort chronologically
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].IsDir() {
+			return filepath.Join(root, entries[i].Name()), nil
+		}
+	}
+	return "", fmt.Errorf("no runs in %v", root)
+}
+
+// newManifest describes the run r made, with the credentials in its config redacted.
+func newManifest(r *Runner, end time.Time, simulated, passed bool) runManifest {
+	cfg := r.cfg
+	for _, secret := range []*string{&cfg.APIKeyID, &cfg.APIKey, &cfg.Webhook} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	return runManifest{
+		RunID:     r.id,
+		Start:     r.start,
+		End:       end,
+		Host:      r.env.Host,
+		Simulated: simulated,
+		Passed:    passed,
+		Config:    cfg,
+		Plan:      r.plan,
+		Profile:   r.profile,
+		Env:       r.env,
+
+		PreviousRun:          r.prevRun,
+		MachineConfigChanges: r.configChanges,
+	}
+}
+
+// writeManifest lists every file in dir plus any extra artifacts written elsewhere, then writes the manifest
+// into dir and returns its path.
+func writeManifest(dir string, m runManifest, extra ...string) (string, error) {
+	m.Artifacts = []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel != manifestFile {
+			m.Artifacts = append(m.Artifacts, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(m.Artifacts)
+	m.Artifacts = append(m.Artifacts, extra...)
+
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, manifestFile)
+	return path, os.WriteFile(path, raw, 0o644)
+}
+
+// readManifest reads the manifest of the run directory dir.
+func readManifest(dir string) (runManifest, error) {
+	var m runManifest
+	raw, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(raw, &m)
+}
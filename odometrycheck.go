@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/utils"
+
+	"rovercanary/samples"
+)
+
+// wheelEncoders are the encoders on the left and right wheels of the bases under test.
+type wheelEncoders struct {
+	left, right encoder.Encoder
+}
+
+// largest allowed divergence between the motion the odometry reports and the one expected from the wheel
+// encoders: a floor that covers the odometry's update lag plus a fraction of the motion so far
+const (
+	odometryDistanceFloor = 50.0 // mm
+	odometryHeadingFloor  = 10.0 // deg
+	odometryDivergence    = 0.1
+)
+
+// odometryCheckName names the odometry check where it is skipped.
+const odometryCheckName = "odometry check"
+
+// encoderDivergence tracks how far the motion the odometry reports diverges from the motion expected from the
+// wheel encoder ticks, from a series of readings of both taken together.
+type encoderDivergence struct {
+	profile hardwareProfile
+
+	started               bool
+	startLeft, startRight float64 // ticks
+	start                 pose
+	heading               headingTracker
+
+	// largest divergence of the odometry's distance (mm) and heading (deg) from the encoders', and largest
+	// distance and heading the encoders measured
+	maxDistance, maxHeading float64
+	encDistance, encHeading float64
+}
+
+// add adds a reading of the left and right encoders in ticks and of the odometry's pose. The first reading is
+// where the motion started.
+func (d *encoderDivergence) add(left, right float64, p pose) {
+	d.heading.add(p.theta)
+	if !d.started {
+		d.started = true
+		d.startLeft, d.startRight, d.start = left, right, p
+		return
+	}
+	distance, heading := d.start.progressTo(p), d.heading.turned()
+	encDistance, encHeading := d.profile.wheelMotion(left-d.startLeft, right-d.startRight)
+	d.maxDistance = math.Max(d.maxDistance, math.Abs(distance-encDistance))
+	d.encDistance = math.Max(d.encDistance, math.Abs(encDistance))
+	if d.profile.WheelBase != 0 {
+		d.maxHeading = math.Max(d.maxHeading, math.Abs(heading-encHeading))
+		d.encHeading = math.Max(d.encHeading, math.Abs(encHeading))
+	}
+}
+
+// check records the largest divergences on res.
+func (d *encoderDivergence) check(res *testResult) error {
+	return checkOdometry(res, d.maxDistance, d.maxHeading, d.encDistance, d.encHeading, d.profile.WheelBase != 0)
+}
+
+// odometryCheck samples the wheel encoders and the odometry while a base moves, tracking how far the odometry
+// diverges from the motion expected from the encoder ticks. It catches encoder miscounts and odometry
+// misconfigured for the rover's wheels. Every reading is recorded on the base's measured samples so a replay
+// can check them again.
+type odometryCheck struct {
+	r        *Runner
+	encs     wheelEncoders
+	odometry movementsensor.MovementSensor
+	data     *samples.Writer
+	testType samples.Type
+	encoderDivergence
+
+	cancel   func()
+	done     chan struct{}
+	stopOnce sync.Once
+	err      error
+}
+
+// startOdometryCheck reads where the encoders and odometry start, then samples them every tickerDuration until
+// the check is stopped. There is no check, and nil is returned, when the rover has no wheel encoders; the
+// skipped check is noted on res.
+func (r *Runner) startOdometryCheck(ctx context.Context, odometry movementsensor.MovementSensor, res *testResult, data *samples.Writer, testType samples.Type) (*odometryCheck, error) {
+	if r.encoders.left == nil || r.encoders.right == nil {
+		res.skipCheck(odometryCheckName, "no wheel encoders configured")
+		return nil, nil
+	}
+	c := &odometryCheck{
+		r:                 r,
+		encs:              r.encoders,
+		odometry:          odometry,
+		data:              data,
+		testType:          testType,
+		encoderDivergence: encoderDivergence{profile: r.profile},
+		done:              make(chan struct{}),
+	}
+	if err := c.sample(ctx); err != nil {
+		return nil, err
+	}
+
+	sampleCtx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	go func() {
+		defer close(c.done)
+		defer r.safety.recoverPanic()
+		for utils.SelectContextOrWait(sampleCtx, tickerDuration) {
+			if err := c.sample(sampleCtx); err != nil {
+				if sampleCtx.Err() == nil {
+					c.err = err
+				}
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+// ticks reads an encoder's position in ticks, converting the degrees of an absolute encoder.
+func (c *odometryCheck) ticks(ctx context.Context, enc encoder.Encoder) (float64, error) {
+	pos, posType, err := enc.Position(ctx, encoder.PositionTypeUnspecified, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error reading encoder %v, err = %w", enc.Name().ShortName(), err)
+	}
+	if posType == encoder.PositionTypeDegrees {
+		return pos / 360 * c.r.profile.TicksPerRotation, nil
+	}
+	return pos, nil
+}
+
+// sample reads both encoders and the odometry's pose once, adds them to the divergences and records them.
+func (c *odometryCheck) sample(ctx context.Context) error {
+	left, err := c.ticks(ctx, c.encs.left)
+	if err != nil {
+		return err
+	}
+	right, err := c.ticks(ctx, c.encs.right)
+	if err != nil {
+		return err
+	}
+	pos, _, err := c.odometry.Position(ctx, c.r.posExtra)
+	if err != nil {
+		return err
+	}
+	orientation, err := c.odometry.Orientation(ctx, nil)
+	if err != nil {
+		return err
+	}
+	c.add(left, right, poseFromOdometry(pos, orientation))
+	c.data.Write(samples.Record{
+		Type:       c.testType,
+		Marker:     samples.Encoders,
+		TimeMs:     c.r.elapsed().Milliseconds(),
+		X:          pos.Lat(),
+		Y:          pos.Lng(),
+		Theta:      orientation.OrientationVectorRadians().Theta,
+		LeftTicks:  left,
+		RightTicks: right,
+	})
+	return nil
+}
+
+// stop stops sampling and waits for the sampler to return. It is safe to call more than once.
+func (c *odometryCheck) stop() {
+	if c == nil {
+		return
+	}
+	c.stopOnce.Do(func() {
+		c.cancel()
+		<-c.done
+	})
+}
+
+// finish stops sampling, takes a last sample of where the base stopped and records the largest divergences.
+func (c *odometryCheck) finish(ctx context.Context, res *testResult) error {
+	if c == nil {
+		return nil
+	}
+	c.stop()
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.sample(ctx); err != nil {
+		return err
+	}
+	return c.check(res)
+}
+
+// replayOdometry checks the odometry against the wheel encoder readings a move straight or spin recorded. A
+// recording without them, made without wheel encoders or before they were recorded, skips the check.
+func replayOdometry(data []samples.Record, profile hardwareProfile, res *testResult) error {
+	d := encoderDivergence{profile: profile}
+	for _, rec := range data {
+		if rec.Marker == samples.Encoders {
+			d.add(rec.LeftTicks, rec.RightTicks, poseFromRecord(rec))
+		}
+	}
+	if !d.started {
+		res.skipCheck(odometryCheckName, "no wheel encoder ticks recorded")
+		return nil
+	}
+	return d.check(res)
+}
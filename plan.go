@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/movementsensor"
+	"gopkg.in/yaml.v3"
+
+	"rovercanary/samples"
+)
+
+// operations a plan step can run
+const (
+	opSetVelocity         = "set_velocity"
+	opConsecutiveVelocity = "consecutive_velocity"
+	opMoveStraight        = "move_straight"
+	opSpin                = "spin"
+	opBaseSetPower        = "base_set_power"
+	opGoFor               = "go_for"
+	opGoTo                = "go_to"
+	opSetRPM              = "set_rpm"
+	opConsecutiveRPM      = "consecutive_rpm"
+	opMotorSetPower       = "motor_set_power"
+)
+
+// default margins used when a step does not set its own
+const (
+	defaultSpeedTolerance    = 0.5
+	defaultDistanceTolerance = 0.3
+	defaultPowerTolerance    = 0.3
+	defaultGoToTolerance     = 1.0
+	defaultDelaySec          = delayBetweenTests
+)
+
+// test timing, timeouts are timeoutFactor times the expected duration plus testTimeoutMargin
+const (
+	sampleSec         = 5.0 // default seconds of steady-state sampling requested from sampleEverything
+	goToZeroOffset    = -2.0
+	timeoutFactor     = 2
+	testTimeoutMargin = 10 * time.Second
+	sensorTestTimeout = 10 * time.Second
+)
+
+// default time each operation is given to reach speed before it is measured
+var defaultSettle = map[string]time.Duration{
+	opSetVelocity:         5 * time.Second,
+	opConsecutiveVelocity: 5 * time.Second,
+	opBaseSetPower:        1 * time.Second,
+	opGoTo:                500 * time.Millisecond,
+	opSetRPM:              5 * time.Second,
+	opConsecutiveRPM:      5 * time.Second,
+	opMotorSetPower:       2 * time.Second,
+}
+
+//go:embed plans/default.yaml
+var defaultPlanYAML []byte
+
+// testPlan lists the steps run against every base and every motor under test, and the path of the grid test.
+type testPlan struct {
+	Base  []planStep `json:"base" yaml:"base"`
+	Motor []planStep `json:"motor" yaml:"motor"`
+	Grid  gridPlan   `json:"grid" yaml:"grid"`
+}
+
+// planStep is a single operation in a test plan along with its parameters, tolerances and timing.
+type planStep struct {
+	Op string `json:"op" yaml:"op"`
+
+	// base parameters
+	Linear     float64 `json:"linear" yaml:"linear"`           // mm/sec
+	Angular    float64 `json:"angular" yaml:"angular"`         // deg/sec
+	NextLinear float64 `json:"next_linear" yaml:"next_linear"` // mm/sec, consecutive_velocity only
+	Distance   float64 `json:"distance" yaml:"distance"`       // mm for move_straight, deg for spin
+	Speed      float64 `json:"speed" yaml:"speed"`             // mm/sec for move_straight, deg/sec for spin
+	TestSpeed  bool    `json:"test_speed" yaml:"test_speed"`   // spin only, check speed instead of duration
+
+	// motor parameters
+	RPM         float64 `json:"rpm" yaml:"rpm"`
+	NextRPM     float64 `json:"next_rpm" yaml:"next_rpm"` // consecutive_rpm only
+	Revolutions float64 `json:"revolutions" yaml:"revolutions"`
+	Position    float64 `json:"position" yaml:"position"`
+
+	// shared parameters
+	Power float64 `json:"power" yaml:"power"` // -1 to 1
+
+	// SpeedTolerance is the allowed speed (or power) error as a fraction of the requested value.
+	SpeedTolerance float64 `json:"speed_tolerance" yaml:"speed_tolerance"`
+	// DistanceTolerance is the allowed distance error as a fraction of the requested distance,
+	// or the allowed end position error in revolutions for go_to.
+	DistanceTolerance float64 `json:"distance_tolerance" yaml:"distance_tolerance"`
+	SettleSec         float64 `json:"settle_sec" yaml:"settle_sec"`
+	SampleSec         float64 `json:"sample_sec" yaml:"sample_sec"` // steady-state sampling window for velocity and rpm steps
+	DelaySec          float64 `json:"delay_sec" yaml:"delay_sec"`
+	// Estimator names how the measured speed is estimated from the samples, see estimators.
+	Estimator string `json:"estimator" yaml:"estimator"`
+	// StepResponse limits the rise, overshoot, settling and tracking error of velocity and rpm steps.
+	StepResponse stepThresholds `json:"step_response" yaml:"step_response"`
+}
+
+// tolerance holds the pass/fail margins and timing for a single test.
+type tolerance struct {
+	speed     float64
+	distance  float64
+	settle    time.Duration
+	sample    time.Duration
+	step      stepThresholds
+	estimator string
+}
+
+// loadPlan reads the plan file at path (JSON or YAML, chosen by extension). An empty path loads the built-in plan.
+func loadPlan(path string) (testPlan, error) {
+	var plan testPlan
+	raw, isYAML := defaultPlanYAML, true
+	if path != "" {
+		var err error
+		if raw, err = os.ReadFile(path); err != nil {
+			return plan, fmt.Errorf("error reading plan file %q, err = %w", path, err)
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		isYAML = ext == ".yaml" || ext == ".yml"
+	}
+
+	var err error
+	if isYAML {
+		err = yaml.Unmarshal(raw, &plan)
+	} else {
+		err = json.Unmarshal(raw, &plan)
+	}
+	if err != nil {
+		return plan, fmt.Errorf("error parsing plan file %q, err = %w", path, err)
+	}
+
+	if err := plan.validate(); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
+
+// validate checks that every step names an operation valid for its section and sets the parameters it divides by.
+func (p testPlan) validate() error {
+	var errs []error
+	baseOps := []string{opSetVelocity, opConsecutiveVelocity, opMoveStraight, opSpin, opBaseSetPower}
+	motorOps := []string{opGoFor, opGoTo, opSetRPM, opConsecutiveRPM, opMotorSetPower}
+	for i, step := range p.Base {
+		if err := step.validate(baseOps); err != nil {
+			errs = append(errs, fmt.Errorf("base step %d: %w", i, err))
+		}
+	}
+	for i, step := range p.Motor {
+		if err := step.validate(motorOps); err != nil {
+			errs = append(errs, fmt.Errorf("motor step %d: %w", i, err))
+		}
+	}
+	if _, err := p.Grid.moves(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (s planStep) validate(allowed []string) error {
+	known := false
+	for _, op := range allowed {
+		if s.Op == op {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown operation %q, expected one of %v", s.Op, allowed)
+	}
+
+	switch s.Op {
+	case opMoveStraight, opSpin:
+		if s.Speed == 0 || s.Distance == 0 {
+			return fmt.Errorf("%v needs a non-zero distance and speed", s.Op)
+		}
+	case opGoFor:
+		if s.RPM == 0 || s.Revolutions == 0 {
+			return fmt.Errorf("%v needs a non-zero rpm and revolutions", s.Op)
+		}
+	case opGoTo, opSetRPM:
+		if s.RPM == 0 {
+			return fmt.Errorf("%v needs a non-zero rpm", s.Op)
+		}
+	case opConsecutiveRPM:
+		if s.RPM == 0 || s.NextRPM == 0 {
+			return fmt.Errorf("%v needs a non-zero rpm and next_rpm", s.Op)
+		}
+	case opBaseSetPower, opMotorSetPower:
+		if s.Power == 0 || s.Power < -1 || s.Power > 1 {
+			return fmt.Errorf("%v needs a power in [-1, 1] that is not zero", s.Op)
+		}
+	}
+
+	if s.Estimator != "" {
+		if _, err := newEstimator(s.Estimator, 0, 0); err != nil {
+			return err
+		}
+	}
+
+	if s.SpeedTolerance < 0 || s.DistanceTolerance < 0 || s.SettleSec < 0 || s.SampleSec < 0 || s.DelaySec < 0 || s.StepResponse.negative() {
+		return errors.New("tolerances and times cannot be negative")
+	}
+	return nil
+}
+
+// tolerance returns the margins for the step, falling back to the defaults for anything unset.
+func (s planStep) tolerance() tolerance {
+	tol := tolerance{
+		speed:    s.SpeedTolerance,
+		distance: s.DistanceTolerance,
+		settle:   time.Duration(s.SettleSec * float64(time.Second)),
+		sample:   time.Duration(s.SampleSec * float64(time.Second)),
+	}
+	if tol.speed == 0 {
+		tol.speed = defaultSpeedTolerance
+		if s.Op == opMotorSetPower {
+			tol.speed = defaultPowerTolerance
+		}
+	}
+	if tol.distance == 0 {
+		tol.distance = defaultDistanceTolerance
+		if s.Op == opGoTo {
+			tol.distance = defaultGoToTolerance
+		}
+	}
+	if tol.settle == 0 {
+		tol.settle = defaultSettle[s.Op]
+	}
+	if tol.sample == 0 {
+		tol.sample = secondsToDuration(sampleSec)
+	}
+	tol.step = s.StepResponse
+	tol.estimator = s.estimator()
+	return tol
+}
+
+// estimator returns the estimator that decides the step's speed, empty for operations that do not estimate one.
+func (s planStep) estimator() string {
+	if s.Estimator == "" {
+		return defaultEstimator[s.Op]
+	}
+	return s.Estimator
+}
+
+// delay returns how long to wait after the step before starting the next one.
+func (s planStep) delay() time.Duration {
+	if s.DelaySec == 0 {
+		return defaultDelaySec * time.Second
+	}
+	return time.Duration(s.DelaySec * float64(time.Second))
+}
+
+// timeout returns the deadline for the step, computed from how long its settle, motion and sampling should take.
+func (s planStep) timeout() time.Duration {
+	tol := s.tolerance()
+	// sampleEverything samples for up to twice the requested window
+	sample := 2 * tol.sample
+
+	var expected time.Duration
+	switch s.Op {
+	case opSetVelocity, opSetRPM:
+		expected = tol.settle + sample
+	case opConsecutiveVelocity:
+		expected = 2*tol.settle + 3*sample
+	case opConsecutiveRPM:
+		expected = 2*tol.settle + 2*sample
+	case opMoveStraight, opSpin:
+		expected = secondsToDuration(s.Distance / s.Speed)
+	case opGoFor:
+		expected = secondsToDuration(s.Revolutions / s.RPM * 60)
+	case opGoTo:
+		expected = tol.settle + secondsToDuration((s.Position+goToZeroOffset)/s.RPM*60)
+	case opBaseSetPower, opMotorSetPower:
+		expected = tol.settle
+	}
+	return timeoutFactor*expected + testTimeoutMargin
+}
+
+// secondsToDuration converts a possibly negative number of seconds to a positive duration.
+func secondsToDuration(sec float64) time.Duration {
+	return time.Duration(math.Abs(sec) * float64(time.Second))
+}
+
+// params returns the non-zero parameters of the step, used to name and describe its result.
+func (s planStep) params() map[string]float64 {
+	all := map[string]float64{
+		"linear":      s.Linear,
+		"angular":     s.Angular,
+		"next_linear": s.NextLinear,
+		"distance":    s.Distance,
+		"speed":       s.Speed,
+		"rpm":         s.RPM,
+		"next_rpm":    s.NextRPM,
+		"revolutions": s.Revolutions,
+		"position":    s.Position,
+		"power":       s.Power,
+	}
+	params := map[string]float64{}
+	for key, val := range all {
+		if val != 0 {
+			params[key] = val
+		}
+	}
+	// go_to to position zero is still a meaningful parameter
+	if s.Op == opGoTo {
+		params["position"] = s.Position
+	}
+	return params
+}
+
+// runBase runs a base step and returns its error, if any.
+func (s planStep) runBase(ctx context.Context, r *Runner, b base.Base, odometry movementsensor.MovementSensor, res *testResult, des, data *samples.Writer) error {
+	tol := s.tolerance()
+	switch s.Op {
+	case opSetVelocity:
+		return r.setVelocityTest(ctx, b, odometry, r3.Vector{Y: s.Linear}, r3.Vector{Z: s.Angular}, tol, res, des, data)
+	case opConsecutiveVelocity:
+		return r.consecutiveVelocityTest(ctx, b, odometry, r3.Vector{Y: s.Linear}, r3.Vector{Y: s.NextLinear}, tol, res, des, data)
+	case opMoveStraight:
+		return r.moveStraightTest(ctx, b, odometry, s.Distance, s.Speed, tol, res, des, data)
+	case opSpin:
+		return r.spinTest(ctx, b, odometry, s.Distance, s.Speed, s.TestSpeed, tol, res, des, data)
+	case opBaseSetPower:
+		return r.baseSetPowerTest(ctx, b, odometry, s.Power, tol, res)
+	default:
+		return fmt.Errorf("unknown base operation %q", s.Op)
+	}
+}
+
+// runMotor runs a motor step and returns its error, if any.
+func (s planStep) runMotor(ctx context.Context, r *Runner, m motor.Motor, odometry movementsensor.MovementSensor, res *testResult, des, data *samples.Writer) error {
+	tol := s.tolerance()
+	switch s.Op {
+	case opGoFor:
+		return r.goForTest(ctx, m, odometry, s.RPM, s.Revolutions, tol, res, des, data)
+	case opGoTo:
+		return r.goToTest(ctx, m, odometry, s.RPM, s.Position, tol, res, des, data)
+	case opSetRPM:
+		return r.setRPMTest(ctx, m, odometry, s.RPM, tol, res, des, data)
+	case opConsecutiveRPM:
+		return r.consecutiveRPMTest(ctx, m, odometry, s.RPM, s.NextRPM, tol, res, des, data)
+	case opMotorSetPower:
+		return r.motorSetPowerTest(ctx, m, s.Power, tol, res)
+	default:
+		return fmt.Errorf("unknown motor operation %q", s.Op)
+	}
+}
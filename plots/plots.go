@@ -0,0 +1,487 @@
+// Package plots draws the jpeg plots of a canary run from the sample files it recorded: actual values in red
+// (or one color per test case for paths) against desired values in blue.
+package plots
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+
+	"rovercanary/samples"
+)
+
+const (
+	width  = 6.4 * vg.Inch
+	height = 4.8 * vg.Inch
+
+	// offset (mm) between the paths of consecutive move straight cases
+	pathOffset = 100
+	// step (rad) desired spin arcs are drawn with
+	arcStep = 0.01
+)
+
+var (
+	actualColor  = color.RGBA{R: 255, A: 255}
+	desiredColor = color.RGBA{B: 255, A: 255}
+	metricsColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	// colors of the actual path of each case, repeated when there are more cases
+	caseColors = []color.Color{
+		color.RGBA{G: 191, B: 191, A: 255},
+		color.RGBA{G: 128, A: 255},
+		color.RGBA{R: 191, G: 191, A: 255},
+		actualColor,
+	}
+)
+
+// A Func plots the samples prefix+"Des.jsonl" and prefix+"Data.jsonl" in dir, titled with the suite name, and
+// returns the paths of the images it wrote. An image that cannot be drawn does not stop the others.
+type Func func(dir, prefix, name string) ([]string, error)
+
+// Base plots a base suite: SetVelocity and MoveStraight velocities, MoveStraight paths and Spin angles.
+func Base(dir, prefix, name string) ([]string, error) {
+	des, data, err := read(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := &output{dir: dir, prefix: prefix}
+
+	for _, t := range []struct {
+		typ   samples.Type
+		title string
+		file  string
+	}{
+		{samples.SetVelocity, "SetVelocity", "sv_vels"},
+		{samples.MoveStraight, "MoveStraight", "ms_vels"},
+	} {
+		lin := timeSeries(t.typ, "linear velocity (mm/sec)", func(r samples.Record) float64 { return r.LinearVelocity })
+		ang := timeSeries(t.typ, "angular velocity (deg/sec)", func(r samples.Record) float64 { return r.AngularVelocity })
+		linPlot, err := lin.plot(fmt.Sprintf("%v Linear and Angular Velocities (%v)", t.title, name), des, data)
+		if err != nil {
+			out.fail(t.file, err)
+			continue
+		}
+		angPlot, err := ang.plot("", des, data)
+		if err != nil {
+			out.fail(t.file, err)
+			continue
+		}
+		angPlot.X.Label.Text = "time (sec)"
+		out.save(t.file, width, 2*height, linPlot, angPlot)
+	}
+
+	if p, err := moveStraightPaths(fmt.Sprintf("MoveStraight Position (%v)", name), des, data); err != nil {
+		out.fail("ms_pos", err)
+	} else {
+		out.save("ms_pos", height, height, p)
+	}
+
+	if p, err := spinAngles(fmt.Sprintf("Spin Angle (%v)", name), des, data); err != nil {
+		out.fail("spin_degs", err)
+	} else {
+		out.save("spin_degs", height, height, p)
+	}
+	return out.paths, out.err
+}
+
+// Motor plots a motor suite: GoFor and GoTo rpm and position, and SetRPM rpm.
+func Motor(dir, prefix, name string) ([]string, error) {
+	des, data, err := read(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := &output{dir: dir, prefix: prefix}
+
+	rpm := func(r samples.Record) float64 { return r.RPM }
+	position := func(r samples.Record) float64 { return r.Position }
+	for _, t := range []struct {
+		series series
+		title  string
+		file   string
+	}{
+		{timeSeries(samples.GoFor, "RPM", rpm), "GoFor RPM", "go_for_rpm"},
+		{timeSeries(samples.GoFor, "Position (rev)", position), "GoFor Position", "go_for_pos"},
+		{timeSeries(samples.GoTo, "RPM", rpm), "GoTo RPM", "go_to_rpm"},
+		{timeSeries(samples.GoTo, "Position (rev)", position), "GoTo Position", "go_to_pos"},
+		{timeSeries(samples.SetRPM, "RPM", rpm), "SetRPM RPM", "set_rpm_rpm"},
+	} {
+		p, err := t.series.plot(fmt.Sprintf("%v (%v)", t.title, name), des, data)
+		if err != nil {
+			out.fail(t.file, err)
+			continue
+		}
+		p.X.Label.Text = "time (sec)"
+		out.save(t.file, width, height, p)
+	}
+	return out.paths, out.err
+}
+
+// GridMetricsFile is the file in the run directory the grid test writes its trajectory metrics to. Grid draws
+// them on the grid plot when it is there.
+const GridMetricsFile = "gridMetrics.json"
+
+// GridMetrics are the errors of the path driven by the grid test against its planned path.
+type GridMetrics struct {
+	Segments []GridSegment `json:"segments"`
+	Spins    []GridSpin    `json:"spins"`
+	// ClosureMm is the distance from where the base ended, End, to where it should have, Goal.
+	End       Point   `json:"end"`
+	Goal      Point   `json:"goal"`
+	ClosureMm float64 `json:"closure_mm"`
+}
+
+// GridSegment is the error of one straight or arc of the grid, planned from From to To through Mid.
+type GridSegment struct {
+	From     Point   `json:"from"`
+	Mid      Point   `json:"mid"`
+	To       Point   `json:"to"`
+	LengthMm float64 `json:"length_mm"`
+	// cross-track errors are the sampled distances from the planned line or arc
+	MaxCrossTrackMm float64 `json:"max_cross_track_mm"`
+	RMSCrossTrackMm float64 `json:"rms_cross_track_mm"`
+	// DistanceErrorMm is how much further than planned the base drove, negative when it fell short
+	DistanceErrorMm float64 `json:"distance_error_mm"`
+}
+
+// GridSpin is the heading error of the base after one spin of the grid, at the point it spun on.
+type GridSpin struct {
+	At              Point   `json:"at"`
+	HeadingErrorDeg float64 `json:"heading_error_deg"`
+}
+
+// Point is a position in m, forward and to the right as in sample records.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// xy returns the point in plot coordinates (mm), y across and x up like path.
+func (pt Point) xy() plotter.XY {
+	return plotter.XY{X: pt.Y * 1000, Y: pt.X * 1000}
+}
+
+// Grid plots the path driven by the grid test against the path it was asked to drive, annotated with its
+// trajectory metrics when the grid test wrote them.
+func Grid(dir, prefix, name string) ([]string, error) {
+	des, data, err := read(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := &output{dir: dir, prefix: prefix}
+
+	p := newPlot(fmt.Sprintf("Grid Test (%v)", name), "y (mm)", "x (mm)")
+	err = addDesired(p, "desired", path(des, samples.Grid))
+	if err == nil {
+		err = addActual(p, "actual", path(data, samples.Grid), actualColor)
+	}
+	if err == nil {
+		err = addGridMetrics(p, filepath.Join(dir, GridMetricsFile))
+	}
+	if err != nil {
+		out.fail("test", err)
+		return out.paths, out.err
+	}
+	equalAxes(p)
+	out.save("test", height, height, p)
+	return out.paths, out.err
+}
+
+// addGridMetrics labels each segment with its cross-track and distance errors and each spin with its heading
+// error, and draws the closure error, when the metrics file exists.
+func addGridMetrics(p *plot.Plot, file string) error {
+	raw, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var m GridMetrics
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return fmt.Errorf("invalid %v, err = %w", GridMetricsFile, err)
+	}
+
+	var labels plotter.XYLabels
+	for i, seg := range m.Segments {
+		labels.XYs = append(labels.XYs, seg.Mid.xy())
+		labels.Labels = append(labels.Labels, fmt.Sprintf("%d: xt %.0f, d %+.0f mm", i+1, seg.MaxCrossTrackMm, seg.DistanceErrorMm))
+	}
+	for _, spin := range m.Spins {
+		labels.XYs = append(labels.XYs, spin.At.xy())
+		labels.Labels = append(labels.Labels, fmt.Sprintf("%+.1f°", spin.HeadingErrorDeg))
+	}
+	if len(labels.XYs) != 0 {
+		l, err := plotter.NewLabels(labels)
+		if err != nil {
+			return err
+		}
+		for i := range l.TextStyle {
+			l.TextStyle[i].Color = metricsColor
+		}
+		p.Add(l)
+	}
+
+	closure, err := plotter.NewLine(plotter.XYs{m.End.xy(), m.Goal.xy()})
+	if err != nil {
+		return err
+	}
+	closure.Color = metricsColor
+	closure.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+	p.Add(closure)
+	p.Legend.Add(fmt.Sprintf("closure %.0f mm", m.ClosureMm), closure)
+	return nil
+}
+
+// read reads the desired and measured samples of a suite.
+func read(dir, prefix string) ([]samples.Record, []samples.Record, error) {
+	_, des, err := samples.ReadFile(filepath.Join(dir, prefix+"Des.jsonl"))
+	if err != nil {
+		return nil, nil, err
+	}
+	_, data, err := samples.ReadFile(filepath.Join(dir, prefix+"Data.jsonl"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return des, data, nil
+}
+
+// output collects the images a suite wrote and the errors of those it could not.
+type output struct {
+	dir    string
+	prefix string
+	paths  []string
+	err    error
+}
+
+func (o *output) fail(file string, err error) {
+	o.err = errors.Join(o.err, fmt.Errorf("%v_%v.jpg: %w", o.prefix, file, err))
+}
+
+// save draws plots stacked top to bottom into one jpeg.
+func (o *output) save(file string, w, h vg.Length, plots ...*plot.Plot) {
+	img := vgimg.New(w, h)
+	dc := draw.New(img)
+	column := make([][]*plot.Plot, len(plots))
+	for i, p := range plots {
+		column[i] = []*plot.Plot{p}
+	}
+	canvases := plot.Align(column, draw.Tiles{Rows: len(plots), Cols: 1}, dc)
+	for i, p := range plots {
+		p.Draw(canvases[i][0])
+	}
+
+	path := filepath.Join(o.dir, fmt.Sprintf("%v_%v.jpg", o.prefix, file))
+	f, err := os.Create(path)
+	if err != nil {
+		o.fail(file, err)
+		return
+	}
+	if _, err := (vgimg.JpegCanvas{Canvas: img}).WriteTo(f); err != nil {
+		f.Close()
+		o.fail(file, err)
+		return
+	}
+	if err := f.Close(); err != nil {
+		o.fail(file, err)
+		return
+	}
+	o.paths = append(o.paths, path)
+}
+
+// series is one field of one record type plotted over time.
+type series struct {
+	typ   samples.Type
+	label string
+	value func(samples.Record) float64
+}
+
+func timeSeries(typ samples.Type, label string, value func(samples.Record) float64) series {
+	return series{typ: typ, label: label, value: value}
+}
+
+// points returns the series' value over time (sec). Start and end markers are left out, they hold positions
+// rather than samples.
+func (s series) points(records []samples.Record) plotter.XYs {
+	var xys plotter.XYs
+	for _, r := range records {
+		if r.Type == s.typ && r.Marker == "" {
+			xys = append(xys, plotter.XY{X: float64(r.TimeMs) / 1000, Y: s.value(r)})
+		}
+	}
+	return xys
+}
+
+// plot draws the series' actual values against its desired values.
+func (s series) plot(title string, des, data []samples.Record) (*plot.Plot, error) {
+	p := newPlot(title, "", s.label)
+	if err := addDesired(p, "desired", s.points(des)); err != nil {
+		return nil, err
+	}
+	if err := addActual(p, "actual", s.points(data), actualColor); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// moveStraightPaths draws each MoveStraight case's path (mm), offset sideways from the previous one, against
+// a straight line from the start to its goal.
+func moveStraightPaths(title string, des, data []samples.Record) (*plot.Plot, error) {
+	p := newPlot(title, "y (mm)", "x (mm)")
+	desCases, dataCases := byCase(des, samples.MoveStraight), byCase(data, samples.MoveStraight)
+	for i, id := range cases(des, samples.MoveStraight) {
+		offset := float64(i * pathOffset)
+
+		// only the last desired record of a case holds the goal
+		goal := desCases[id][len(desCases[id])-1].X * 1000
+		desired := plotter.XYs{{X: offset, Y: 0}, {X: offset, Y: goal}}
+		if err := addDesired(p, fmt.Sprintf("desired y=%v", offset), desired); err != nil {
+			return nil, err
+		}
+
+		var actual plotter.XYs
+		for _, r := range dataCases[id] {
+			actual = append(actual, plotter.XY{X: r.Y*1000 + offset, Y: r.X * 1000})
+		}
+		if err := addActual(p, fmt.Sprintf("actual %d", i+1), actual, caseColors[i%len(caseColors)]); err != nil {
+			return nil, err
+		}
+	}
+	equalAxes(p)
+	return p, nil
+}
+
+// spinAngles draws each Spin case's heading on a circle of its own radius against an arc of the angle asked for.
+func spinAngles(title string, des, data []samples.Record) (*plot.Plot, error) {
+	p := newPlot(title, "", "")
+	desCases, dataCases := byCase(des, samples.Spin), byCase(data, samples.Spin)
+	for i, id := range cases(des, samples.Spin) {
+		radius := float64(i + 1)
+
+		// only the last desired record of a case holds the goal
+		goal := desCases[id][len(desCases[id])-1].Theta
+		var desired plotter.XYs
+		for theta := 0.0; theta <= math.Abs(goal); theta += arcStep {
+			desired = append(desired, onCircle(radius, math.Copysign(theta, goal)))
+		}
+		if err := addDesired(p, fmt.Sprintf("desired r=%v", radius), desired); err != nil {
+			return nil, err
+		}
+
+		var actual plotter.XYs
+		for _, r := range dataCases[id] {
+			actual = append(actual, onCircle(radius, r.Theta))
+		}
+		if err := addActual(p, fmt.Sprintf("actual %d", i+1), actual, caseColors[i%len(caseColors)]); err != nil {
+			return nil, err
+		}
+	}
+	equalAxes(p)
+	return p, nil
+}
+
+func onCircle(radius, theta float64) plotter.XY {
+	return plotter.XY{X: radius * math.Cos(theta), Y: radius * math.Sin(theta)}
+}
+
+// path returns the x and y (mm) of the records of a type, plotted with y across and x up like the rover's frame.
+// Markers are left out, they may hold only a heading.
+func path(records []samples.Record, typ samples.Type) plotter.XYs {
+	var xys plotter.XYs
+	for _, r := range records {
+		if r.Type == typ && r.Marker == "" {
+			xys = append(xys, plotter.XY{X: r.Y * 1000, Y: r.X * 1000})
+		}
+	}
+	return xys
+}
+
+// cases returns the IDs of the test cases with records of a type, in the order they ran.
+func cases(records []samples.Record, typ samples.Type) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, r := range records {
+		if r.Type == typ && !seen[r.Case] {
+			seen[r.Case] = true
+			ids = append(ids, r.Case)
+		}
+	}
+	return ids
+}
+
+// byCase groups the records of a type by test case.
+func byCase(records []samples.Record, typ samples.Type) map[string][]samples.Record {
+	grouped := map[string][]samples.Record{}
+	for _, r := range records {
+		if r.Type == typ {
+			grouped[r.Case] = append(grouped[r.Case], r)
+		}
+	}
+	return grouped
+}
+
+func newPlot(title, xLabel, yLabel string) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = yLabel
+	p.Add(plotter.NewGrid())
+	p.Legend.Top = true
+	return p
+}
+
+// addDesired adds a desired line, nothing is added when there are no points.
+func addDesired(p *plot.Plot, label string, xys plotter.XYs) error {
+	if len(xys) == 0 {
+		return nil
+	}
+	line, err := plotter.NewLine(xys)
+	if err != nil {
+		return err
+	}
+	line.Color = desiredColor
+	p.Add(line)
+	p.Legend.Add(label, line)
+	return nil
+}
+
+// addActual adds an actual line with a dot at every sample, nothing is added when there are no points.
+func addActual(p *plot.Plot, label string, xys plotter.XYs, c color.Color) error {
+	if len(xys) == 0 {
+		return nil
+	}
+	line, points, err := plotter.NewLinePoints(xys)
+	if err != nil {
+		return err
+	}
+	line.Color = c
+	points.Color = c
+	points.Shape = draw.CircleGlyph{}
+	points.Radius = vg.Points(1.5)
+	p.Add(line, points)
+	p.Legend.Add(label, line)
+	return nil
+}
+
+// equalAxes gives both axes the same range so paths and circles are not distorted on a square image.
+func equalAxes(p *plot.Plot) {
+	if p.X.Min > p.X.Max || p.Y.Min > p.Y.Max {
+		// nothing was plotted
+		return
+	}
+	span := math.Max(p.X.Max-p.X.Min, p.Y.Max-p.Y.Min)
+	if span == 0 {
+		span = 1
+	}
+	xMid, yMid := (p.X.Max+p.X.Min)/2, (p.Y.Max+p.Y.Min)/2
+	p.X.Min, p.X.Max = xMid-span/2, xMid+span/2
+	p.Y.Min, p.Y.Max = yMid-span/2, yMid+span/2
+}
@@ -0,0 +1,153 @@
+package plots
+
+import (
+	"encoding/json"
+	"image"
+	_ "image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.viam.com/test"
+
+	"rovercanary/samples"
+)
+
+// writeSamples writes a suite's desired and measured sample files into dir.
+func writeSamples(t *testing.T, dir, prefix string, des, data []samples.Record) {
+	t.Helper()
+	for _, file := range []struct {
+		name    string
+		kind    samples.Kind
+		records []samples.Record
+	}{
+		{prefix + "Des.jsonl", samples.Desired, des},
+		{prefix + "Data.jsonl", samples.Measured, data},
+	} {
+		f, err := os.Create(filepath.Join(dir, file.name))
+		test.That(t, err, test.ShouldBeNil)
+		w, err := samples.NewWriter(f, "run", prefix, file.kind)
+		test.That(t, err, test.ShouldBeNil)
+		for _, r := range file.records {
+			test.That(t, w.Write(r), test.ShouldBeNil)
+		}
+		test.That(t, f.Close(), test.ShouldBeNil)
+	}
+}
+
+// checkImages checks that every path is a jpeg in dir with one of the names.
+func checkImages(t *testing.T, dir string, paths []string, names ...string) {
+	t.Helper()
+	var want []string
+	for _, name := range names {
+		want = append(want, filepath.Join(dir, name))
+	}
+	test.That(t, paths, test.ShouldResemble, want)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		test.That(t, err, test.ShouldBeNil)
+		_, format, err := image.DecodeConfig(f)
+		f.Close()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, format, test.ShouldEqual, "jpeg")
+	}
+}
+
+func TestBase(t *testing.T) {
+	dir := t.TempDir()
+	des := []samples.Record{
+		{Case: "00-set_velocity", Type: samples.SetVelocity, TimeMs: 0, LinearVelocity: 100},
+		{Case: "00-set_velocity", Type: samples.SetVelocity, TimeMs: 1000, LinearVelocity: 100},
+		{Case: "01-move_straight", Type: samples.MoveStraight, TimeMs: 1000},
+		{Case: "01-move_straight", Type: samples.MoveStraight, TimeMs: 2000, LinearVelocity: 100, X: 0.1},
+		{Case: "02-spin", Type: samples.Spin, TimeMs: 2000, AngularVelocity: 45},
+		{Case: "02-spin", Type: samples.Spin, TimeMs: 3000, AngularVelocity: 45, Theta: 1.57},
+	}
+	data := []samples.Record{
+		{Case: "00-set_velocity", Type: samples.SetVelocity, TimeMs: 500, LinearVelocity: 98},
+		{Case: "01-move_straight", Type: samples.MoveStraight, Marker: samples.Start, TimeMs: 1000},
+		{Case: "01-move_straight", Type: samples.MoveStraight, TimeMs: 1500, LinearVelocity: 101, X: 0.05},
+		{Case: "01-move_straight", Type: samples.MoveStraight, Marker: samples.End, TimeMs: 2000, X: 0.099},
+		{Case: "02-spin", Type: samples.Spin, TimeMs: 2500, AngularVelocity: 44, Theta: 0.8},
+	}
+	writeSamples(t, dir, "sensor", des, data)
+
+	paths, err := Base(dir, "sensor", "Sensor Base")
+	test.That(t, err, test.ShouldBeNil)
+	checkImages(t, dir, paths, "sensor_sv_vels.jpg", "sensor_ms_vels.jpg", "sensor_ms_pos.jpg", "sensor_spin_degs.jpg")
+}
+
+func TestMotor(t *testing.T) {
+	dir := t.TempDir()
+	des := []samples.Record{
+		{Case: "00-go_for", Type: samples.GoFor, TimeMs: 0},
+		{Case: "00-go_for", Type: samples.GoFor, TimeMs: 0, RPM: 60},
+		{Case: "00-go_for", Type: samples.GoFor, TimeMs: 1000, RPM: 60, Position: 1},
+		{Case: "00-go_for", Type: samples.GoFor, TimeMs: 1000, Position: 1},
+	}
+	data := []samples.Record{
+		{Case: "00-go_for", Type: samples.GoFor, TimeMs: 500, RPM: 59, Position: 0.5},
+		{Case: "00-go_for", Type: samples.GoFor, Marker: samples.End, TimeMs: 1000, Position: 1},
+	}
+	writeSamples(t, dir, "encoded", des, data)
+
+	// tests without samples still get a plot
+	paths, err := Motor(dir, "encoded", "Encoded Motor")
+	test.That(t, err, test.ShouldBeNil)
+	checkImages(t, dir, paths, "encoded_go_for_rpm.jpg", "encoded_go_for_pos.jpg", "encoded_go_to_rpm.jpg",
+		"encoded_go_to_pos.jpg", "encoded_set_rpm_rpm.jpg")
+}
+
+func TestGrid(t *testing.T) {
+	dir := t.TempDir()
+	des := []samples.Record{
+		{Case: "grid", Type: samples.Grid, TimeMs: 0},
+		{Case: "grid", Type: samples.Grid, TimeMs: 1000, X: 0.5},
+	}
+	data := []samples.Record{
+		{Case: "grid", Type: samples.Grid, TimeMs: 500, X: 0.2, Y: 0.01},
+		{Case: "grid", Type: samples.Grid, TimeMs: 1000, X: 0.49, Y: 0.02},
+		// spins only record their heading on their markers
+		{Case: "grid", Type: samples.Grid, Marker: samples.Start, TimeMs: 1100},
+		{Case: "grid", Type: samples.Grid, Marker: samples.End, TimeMs: 2000, Theta: 1.6},
+	}
+	writeSamples(t, dir, "grid", des, data)
+	test.That(t, path(data, samples.Grid), test.ShouldHaveLength, 2)
+
+	paths, err := Grid(dir, "grid", "Sensor Base")
+	test.That(t, err, test.ShouldBeNil)
+	checkImages(t, dir, paths, "grid_test.jpg")
+
+	// with the trajectory metrics of the grid test
+	metrics, err := json.Marshal(GridMetrics{
+		Segments:  []GridSegment{{From: Point{}, Mid: Point{X: 0.25}, To: Point{X: 0.5}, LengthMm: 500, MaxCrossTrackMm: 20, DistanceErrorMm: -10}},
+		Spins:     []GridSpin{{At: Point{X: 0.49, Y: 0.02}, HeadingErrorDeg: 1.7}},
+		End:       Point{X: 0.49, Y: 0.02},
+		Goal:      Point{X: 0.5},
+		ClosureMm: 22,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, os.WriteFile(filepath.Join(dir, GridMetricsFile), metrics, 0o644), test.ShouldBeNil)
+	paths, err = Grid(dir, "grid", "Sensor Base")
+	test.That(t, err, test.ShouldBeNil)
+	checkImages(t, dir, paths, "grid_test.jpg")
+
+	test.That(t, os.WriteFile(filepath.Join(dir, GridMetricsFile), []byte("not json"), 0o644), test.ShouldBeNil)
+	_, err = Grid(dir, "grid", "Sensor Base")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, GridMetricsFile)
+}
+
+func TestPlotErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Grid(dir, "grid", "Sensor Base")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	// an image that cannot be written is reported with its name
+	writeSamples(t, dir, "grid", []samples.Record{{Type: samples.Grid, X: 0.5}}, nil)
+	test.That(t, os.Mkdir(filepath.Join(dir, "grid_test.jpg"), 0o755), test.ShouldBeNil)
+	paths, err := Grid(dir, "grid", "Sensor Base")
+	test.That(t, paths, test.ShouldBeEmpty)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "grid_test.jpg")
+}
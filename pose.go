@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+
+	"rovercanary/samples"
+)
+
+// pose is the planar pose of a base relative to where its odometry was last reset: x forward and y to the left
+// of the starting heading in mm, theta counterclockwise from the starting heading in rad.
+//
+// Odometry reports its relative position (with return_relative_pos_m) as a point whose latitude is meters
+// forward and longitude meters to the right, and sample records keep those values as x and y.
+type pose struct {
+	point r3.Vector
+	theta float64
+}
+
+// poseFromOdometry converts a relative odometry position and orientation to a pose.
+func poseFromOdometry(pos *geo.Point, o spatialmath.Orientation) pose {
+	p := pose{point: r3.Vector{X: pos.Lat() * 1000, Y: -pos.Lng() * 1000}}
+	if o != nil {
+		p.theta = o.OrientationVectorRadians().Theta
+	}
+	return p
+}
+
+// poseFromRecord converts the position and heading of a sample record to a pose.
+func poseFromRecord(rec samples.Record) pose {
+	return pose{point: r3.Vector{X: rec.X * 1000, Y: -rec.Y * 1000}, theta: rec.Theta}
+}
+
+// record returns the pose as the x, y (m) and theta (rad) of a sample record.
+func (p pose) record() (x, y, theta float64) {
+	return p.point.X / 1000, -p.point.Y / 1000, p.theta
+}
+
+// moved returns the pose after driving straight for distance mm along its heading, backwards when negative.
+func (p pose) moved(distance float64) pose {
+	p.point = p.point.Add(p.heading().Mul(distance))
+	return p
+}
+
+// turned returns the pose after spinning deg degrees counterclockwise.
+func (p pose) turned(deg float64) pose {
+	p.theta = wrapAngle(p.theta + rdkutils.DegToRad(deg))
+	return p
+}
+
+// arced returns the pose after driving forward along an arc of radius mm through deg degrees, turning left
+// when deg is positive.
+func (p pose) arced(radius, deg float64) pose {
+	center := p.arcCenter(radius, deg)
+	rad := rdkutils.DegToRad(deg)
+	d := p.point.Sub(center)
+	sin, cos := math.Sincos(rad)
+	p.point = center.Add(r3.Vector{X: d.X*cos - d.Y*sin, Y: d.X*sin + d.Y*cos})
+	p.theta = wrapAngle(p.theta + rad)
+	return p
+}
+
+// arcCenter is the center of an arc of radius mm starting at the pose, to its left when deg is positive.
+func (p pose) arcCenter(radius, deg float64) r3.Vector {
+	h := p.heading()
+	left := r3.Vector{X: -h.Y, Y: h.X}
+	return p.point.Add(left.Mul(radius * sign(deg)))
+}
+
+// heading is the unit vector the pose faces.
+func (p pose) heading() r3.Vector {
+	return r3.Vector{X: math.Cos(p.theta), Y: math.Sin(p.theta)}
+}
+
+// distanceTo is the straight line distance in mm to another pose.
+func (p pose) distanceTo(q pose) float64 {
+	return p.point.Distance(q.point)
+}
+
+// progressTo is how far another pose is along this pose's heading in mm, negative when behind it.
+func (p pose) progressTo(q pose) float64 {
+	return q.point.Sub(p.point).Dot(p.heading())
+}
+
+// crossTrackTo is how far another pose is to the left of the line through this pose along its heading in mm,
+// negative when to the right.
+func (p pose) crossTrackTo(q pose) float64 {
+	d := q.point.Sub(p.point)
+	h := p.heading()
+	return h.X*d.Y - h.Y*d.X
+}
+
+// headingTracker unwraps a stream of sampled headings into the total signed rotation since the first, so a
+// spin of more than a turn, or one that reversed, is measured as it happened. Samples must be less than half
+// a turn apart.
+type headingTracker struct {
+	started bool
+	last    float64 // rad
+	total   float64 // rad
+}
+
+// add adds a sampled heading in rad.
+func (h *headingTracker) add(theta float64) {
+	if h.started {
+		h.total += wrapAngle(theta - h.last)
+	}
+	h.last, h.started = theta, true
+}
+
+// turned is the total signed rotation in degrees, counterclockwise positive.
+func (h *headingTracker) turned() float64 {
+	return rdkutils.RadToDeg(h.total)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/components/powersensor"
+	"go.viam.com/rdk/resource"
+)
+
+// gravity and the allowed error on the imu's z acceleration, as a fraction of the profile's value, while
+// the rover is level and idle
+const (
+	gravity          = 9.81
+	gravityTolerance = 0.5
+)
+
+// minBatteryVoltage returns the lowest pack voltage motion suites are allowed to start at. The config
+// threshold is used when set, otherwise the bottom of the profile's nominal voltage range.
+func (cfg canaryConfig) minBatteryVoltage(profile hardwareProfile) float64 {
+	if cfg.MinBatteryVoltage != 0 {
+		return cfg.MinBatteryVoltage
+	}
+	return profile.Voltage - profile.VoltageTolerance
+}
+
+// preflight reads the battery and imu before any motion and records a result for each reading. It returns
+// an error describing every failed precondition, in which case motion suites must not run. A failed
+// precondition is recorded as skipped with its reason rather than failed, a flat battery is not a regression.
+// Sensors whose role is not configured are not checked.
+func (r *Runner) preflight(ctx context.Context, ps powersensor.PowerSensor, imu movementsensor.MovementSensor) error {
+	ctx, cancel := context.WithTimeout(ctx, sensorTestTimeout)
+	defer cancel()
+
+	minVoltage := r.cfg.minBatteryVoltage(r.profile)
+	var errs []error
+	record := func(res *testResult, err error) {
+		res.finish(err)
+		if err != nil {
+			res.skip(fmt.Sprintf("precondition failed: %v", err))
+			errs = append(errs, fmt.Errorf("%v %v: %v", res.Component, res.Operation, err))
+		}
+		r.results.add(res)
+	}
+
+	if ps == nil {
+		r.logger.Warn("power_sensor is not configured, starting motion suites without checking the battery")
+	} else {
+		name := ps.Name().ShortName()
+
+		res := newResult(suitePreflight, name, "battery_voltage", nil)
+		volts, _, err := ps.Voltage(ctx, nil)
+		if err != nil {
+			err = fmt.Errorf("power sensor is unreachable, err = %v", err)
+		} else if !res.checkMin("voltage", volts, minVoltage) {
+			err = fmt.Errorf("battery voltage %v is below the minimum of %v", volts, minVoltage)
+		}
+		record(res, err)
+
+		// current and power are read to confirm the sensor is healthy, their nominal values are checked by
+		// the power sensor suite
+		res = newResult(suitePreflight, name, "battery_current", nil)
+		current, _, err := ps.Current(ctx, nil)
+		if err != nil {
+			err = fmt.Errorf("power sensor is unreachable, err = %v", err)
+		} else {
+			r.logger.Infof("pre-flight current = %v A", current)
+		}
+		record(res, err)
+
+		res = newResult(suitePreflight, name, "battery_power", nil)
+		power, err := ps.Power(ctx, nil)
+		if err != nil {
+			err = fmt.Errorf("power sensor is unreachable, err = %v", err)
+		} else {
+			r.logger.Infof("pre-flight power = %v W", power)
+		}
+		record(res, err)
+	}
+
+	if imu == nil {
+		r.logger.Warn("movement_sensor is not configured, starting motion suites without checking the imu")
+	} else {
+		res := newResult(suitePreflight, imu.Name().ShortName(), "gravity", nil)
+		accel, err := imu.LinearAcceleration(ctx, nil)
+		if err != nil {
+			err = fmt.Errorf("imu is unreachable, err = %v", err)
+		} else if !res.check("linear acceleration z", accel.Z, r.profile.Gravity, r.profile.Gravity*gravityTolerance) {
+			err = fmt.Errorf("imu does not read gravity, linear acceleration z = %v", accel.Z)
+		}
+		record(res, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// canMove is canRun for suites that drive the rover, which are also skipped when pre-flight failed.
+func (r *Runner) canMove(suite string, precondition error, deps ...resource.Resource) bool {
+	if precondition != nil {
+		r.logger.Warnf("skipping %v tests, pre-flight failed", suite)
+		res := newResult(suite, "", "suite", nil)
+		res.skip(fmt.Sprintf("precondition failed: %v", precondition))
+		r.results.add(res)
+		return false
+	}
+	return r.canRun(suite, deps...)
+}
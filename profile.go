@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// names of the built-in hardware profiles
+const (
+	profileRoverV1 = "viam-rover-v1"
+	profileRoverV2 = "viam-rover-v2"
+	profileCustom  = "custom"
+
+	defaultProfile = profileRoverV2
+)
+
+// hardwareProfile bundles the physical constants and nominal electrical values of a rover model.
+type hardwareProfile struct {
+	Name               string  `json:"name" yaml:"name"`
+	TicksPerRotation   float64 `json:"ticks_per_rotation" yaml:"ticks_per_rotation"`
+	WheelCircumference float64 `json:"wheel_circumference_mm" yaml:"wheel_circumference_mm"`
+	// WheelBase is the distance between the left and right wheels, the odometry check skips headings without it
+	WheelBase float64 `json:"wheel_base_mm" yaml:"wheel_base_mm"`
+
+	// nominal readings from the power sensor while the rover is idle, with the allowed error for each
+	Voltage          float64 `json:"voltage" yaml:"voltage"`
+	VoltageTolerance float64 `json:"voltage_tolerance" yaml:"voltage_tolerance"`
+	Current          float64 `json:"current" yaml:"current"`
+	CurrentTolerance float64 `json:"current_tolerance" yaml:"current_tolerance"`
+	Power            float64 `json:"power" yaml:"power"`
+	PowerTolerance   float64 `json:"power_tolerance" yaml:"power_tolerance"`
+
+	// Gravity is the z acceleration the imu reads while the rover is level, defaults to 9.81 m/s^2
+	Gravity float64 `json:"gravity" yaml:"gravity"`
+}
+
+// hardwareProfiles are the built-in rover models a config or --profile selects. Other rovers use a custom
+// profile.
+var hardwareProfiles = map[string]hardwareProfile{
+	// the v1 has smaller wheels closer together than the v2, driven by the same 1992 tick gearmotors. It is
+	// judged against the v2's idle pack readings, a v1 on a different pack needs a custom profile.
+	profileRoverV1: {
+		Name:               profileRoverV1,
+		TicksPerRotation:   1992.0,
+		WheelCircumference: 217.0,
+		WheelBase:          260.0,
+		Voltage:            15.2,
+		VoltageTolerance:   1.5,
+		Current:            0.29,
+		CurrentTolerance:   0.15,
+		Power:              4.4,
+		PowerTolerance:     1.5,
+		Gravity:            gravity,
+	},
+	profileRoverV2: {
+		Name:               profileRoverV2,
+		TicksPerRotation:   1992.0,
+		WheelCircumference: 381.0,
+		WheelBase:          356.0,
+		Voltage:            15.2,
+		VoltageTolerance:   1.5,
+		Current:            0.29,
+		CurrentTolerance:   0.15,
+		Power:              4.4,
+		PowerTolerance:     1.5,
+		Gravity:            gravity,
+	},
+}
+
+// wheelMotion is the motion of the base expected from how far its left and right wheels turned, in encoder
+// ticks: the distance it drove forward in mm and how far it turned counterclockwise in degrees, zero without a
+// wheel base.
+func (p hardwareProfile) wheelMotion(left, right float64) (float64, float64) {
+	mmPerTick := p.WheelCircumference / p.TicksPerRotation
+	distance := (left + right) / 2 * mmPerTick
+	if p.WheelBase == 0 {
+		return distance, 0
+	}
+	return distance, rdkutils.RadToDeg((right - left) * mmPerTick / p.WheelBase)
+}
+
+// hardwareProfile returns the profile selected by the config, defaulting to the v2 rover.
+func (cfg canaryConfig) hardwareProfile() (hardwareProfile, error) {
+	switch cfg.Profile {
+	case "":
+		return hardwareProfiles[defaultProfile], nil
+	case profileCustom:
+		profile := cfg.CustomProfile
+		profile.Name = profileCustom
+		if profile.Gravity == 0 {
+			profile.Gravity = gravity
+		}
+		if err := profile.validate(); err != nil {
+			return profile, fmt.Errorf("invalid custom hardware profile, err = %w", err)
+		}
+		return profile, nil
+	}
+
+	profile, ok := hardwareProfiles[cfg.Profile]
+	if !ok {
+		names := []string{profileCustom}
+		for name := range hardwareProfiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return profile, fmt.Errorf("unknown hardware profile %q, expected one of %v", cfg.Profile, names)
+	}
+	return profile, nil
+}
+
+// validate checks that every physical constant and nominal value is set.
+func (p hardwareProfile) validate() error {
+	var errs []error
+	fields := []struct {
+		name string
+		val  float64
+	}{
+		{"ticks_per_rotation", p.TicksPerRotation},
+		{"wheel_circumference_mm", p.WheelCircumference},
+		{"voltage", p.Voltage},
+		{"voltage_tolerance", p.VoltageTolerance},
+		{"current", p.Current},
+		{"current_tolerance", p.CurrentTolerance},
+		{"power", p.Power},
+		{"power_tolerance", p.PowerTolerance},
+		{"gravity", p.Gravity},
+	}
+	for _, field := range fields {
+		if field.val <= 0 {
+			errs = append(errs, fmt.Errorf("%v must be greater than zero", field.name))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.viam.com/rdk/logging"
+
+	"rovercanary/samples"
+)
+
+// recorded sample files replayed for each suite and whether it ran the base or the motor plan
+var replaySuites = []struct {
+	suite  string
+	prefix string
+	motor  bool
+}{
+	{suiteWheeledBase, "wheeled", false},
+	{suiteSensorBase, "sensor", false},
+	{suiteEncodedMotor, "encoded", true},
+	{suiteControlledMotor, "controlled", true},
+}
+
+// gridMarkersVersion is the sample schema version grid files were first written with the markers a replay needs
+const gridMarkersVersion = 3
+
+// recording is the desired and measured records of one suite grouped by test case.
+type recording struct {
+	des  map[string][]samples.Record
+	data map[string][]samples.Record
+}
+
+// newRecording groups a suite's desired and measured records by test case.
+func newRecording(des, data []samples.Record) *recording {
+	rec := &recording{des: map[string][]samples.Record{}, data: map[string][]samples.Record{}}
+	for _, r := range des {
+		rec.des[r.Case] = append(rec.des[r.Case], r)
+	}
+	for _, r := range data {
+		rec.data[r.Case] = append(rec.data[r.Case], r)
+	}
+	return rec
+}
+
+// runReplay re-judges a recorded run from its *Des and *Data files with the current estimators and
+// tolerances, writes reports for it and returns the process exit code.
+func runReplay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", "canary.json", "path to the canary config file, used when the run has no manifest")
+	planPath := flags.String("plan", "", "test plan to judge the run against, overrides the one it ran")
+	runsDir := flags.String("runs-dir", "./runs", "directory the run directories are in")
+	runID := flags.String("run", "", "id of the run to replay, defaults to the latest run")
+	reportDir := flags.String("report-dir", "./reports/replay", "directory to write the junit xml and json reports to")
+	flags.Parse(args)
+
+	logger := logging.NewLogger("replay")
+
+	runDir := filepath.Join(*runsDir, *runID)
+	if *runID == "" {
+		var err error
+		if runDir, err = latestRunDir(*runsDir); err != nil {
+			logger.Fatalf("no run to replay, err = %v", err)
+		}
+	}
+
+	// judge the run against the config and plan it ran with when it recorded them
+	manifest, err := readManifest(runDir)
+	if err != nil {
+		logger.Infof("no manifest in %v, using %v, err = %v", runDir, *configPath, err)
+		if _, err := os.Stat(*configPath); err != nil {
+			*configPath = ""
+		}
+		cfg, err := readConfig(*configPath)
+		if err != nil {
+			logger.Fatalf("invalid canary config, err = %v", err)
+		}
+		if manifest.Profile, err = cfg.hardwareProfile(); err != nil {
+			logger.Fatalf("invalid hardware profile, err = %v", err)
+		}
+		if manifest.Plan, err = loadPlan(cfg.Plan); err != nil {
+			logger.Fatalf("invalid test plan, err = %v", err)
+		}
+		manifest.Config = cfg
+	}
+	if *planPath != "" {
+		if manifest.Plan, err = loadPlan(*planPath); err != nil {
+			logger.Fatalf("invalid test plan, err = %v", err)
+		}
+	}
+
+	start := time.Now()
+	results := &resultCollector{}
+	components := map[string]string{
+		suiteWheeledBase:     manifest.Config.Components.WheeledBase,
+		suiteSensorBase:      manifest.Config.Components.SensorBase,
+		suiteEncodedMotor:    manifest.Config.Components.LeftMotor,
+		suiteControlledMotor: manifest.Config.Components.RightMotor,
+	}
+	for _, s := range replaySuites {
+		rec, err := readRecording(runDir, s.prefix)
+		if err != nil {
+			logger.Infof("not replaying %v, err = %v", s.suite, err)
+			continue
+		}
+		logger.Infof("replaying %v from %v", s.suite, runDir)
+		steps := manifest.Plan.Base
+		if s.motor {
+			steps = manifest.Plan.Motor
+		}
+		for i, step := range steps {
+			res := newResult(s.suite, components[s.suite], step.Op, step.params())
+			res.Estimator = step.estimator()
+			replayStep(step, caseID(i, step.Op), rec, manifest.Profile, res)
+			results.add(res)
+		}
+	}
+
+	// the grid is replayed from the poses it recorded rather than step by step
+	if header, data, err := samples.ReadFile(filepath.Join(runDir, "gridData.jsonl")); err != nil {
+		logger.Infof("not replaying %v, err = %v", suiteGrid, err)
+	} else {
+		logger.Infof("replaying %v from %v", suiteGrid, runDir)
+		res := newResult(suiteGrid, manifest.Config.Components.SensorBase, "grid", nil)
+		res.Name = "grid pattern=" + manifest.Plan.Grid.name()
+		res.Case = "grid"
+		replayGrid(manifest.Plan.Grid, header, data, res)
+		results.add(res)
+	}
+
+	summary := results.summary()
+	logger.Infof("%d tests replayed: %d passed, %d failed, %d errored, %d skipped",
+		summary.Total, summary.Passed, summary.Failed, summary.Errored, summary.Skipped)
+
+	report := newRunReport(start, time.Now(), manifest.Profile, manifest.Env, results)
+	junitPath, jsonPath, err := writeReports(*reportDir, filepath.Base(runDir), report)
+	if err != nil {
+		logger.Errorf("error writing reports, err = %v", err)
+	} else {
+		logger.Infof("wrote reports %v and %v", junitPath, jsonPath)
+	}
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// readRecording reads the desired and measured records of the suite whose sample files start with prefix.
+func readRecording(runDir, prefix string) (*recording, error) {
+	_, des, err := samples.ReadFile(filepath.Join(runDir, prefix+"Des.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	_, data, err := samples.ReadFile(filepath.Join(runDir, prefix+"Data.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	return newRecording(des, data), nil
+}
+
+// segment returns the desired records of a case in the pairs of goal rows consecutive tests write, and for each
+// the measured samples taken between them. Start markers are not goal rows.
+func segment(des, data []samples.Record, n int) ([][]samples.Record, bool) {
+	var goals []samples.Record
+	for _, r := range des {
+		if r.Marker == "" {
+			goals = append(goals, r)
+		}
+	}
+	des = goals
+	if len(des) != 2*n {
+		return nil, false
+	}
+	segments := make([][]samples.Record, n)
+	for i := range segments {
+		segments[i] = window(data, des[2*i:2*i+2])
+	}
+	return segments, true
+}
+
+// window returns the samples, without start and end markers, taken while the desired records were written.
+func window(data, des []samples.Record) []samples.Record {
+	from, to := des[0].TimeMs, des[len(des)-1].TimeMs
+	var rows []samples.Record
+	for _, row := range data {
+		if row.Marker == "" && row.TimeMs >= from && row.TimeMs <= to {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// end returns the record of where a motion ended.
+func end(data []samples.Record) (samples.Record, error) {
+	for i := len(data) - 1; i >= 0; i-- {
+		if data[i].Marker == samples.End {
+			return data[i], nil
+		}
+	}
+	return samples.Record{}, errors.New("no end position recorded")
+}
+
+// replayStep re-judges one plan step from the records of its test case on a rover with the given profile,
+// finishing res the way the live test would have.
+func replayStep(step planStep, id string, rec *recording, profile hardwareProfile, res *testResult) {
+	res.Case = id
+	tol := step.tolerance()
+	des, data := rec.des[id], rec.data[id]
+	if step.Op == opBaseSetPower || step.Op == opMotorSetPower {
+		res.skip("set power tests record no samples")
+		return
+	}
+	if len(des) == 0 {
+		res.skip("no samples recorded for this test case")
+		return
+	}
+
+	// velocity and rpm tests are judged on the samples between each pair of goal records
+	var segments [][]samples.Record
+	switch step.Op {
+	case opSetVelocity, opSetRPM, opConsecutiveVelocity, opConsecutiveRPM:
+		n := 1
+		if step.Op == opConsecutiveVelocity || step.Op == opConsecutiveRPM {
+			n = 2
+		}
+		var ok bool
+		if segments, ok = segment(des, data, n); !ok {
+			res.skip("incomplete recording for this test case")
+			return
+		}
+	}
+
+	// and on their step responses when the recording has them
+	var stepErr error
+	switch step.Op {
+	case opSetVelocity:
+		stepErr = checkSteps(res, des, data, velocityFields, tol)
+	case opConsecutiveVelocity:
+		stepErr = checkSteps(res, des, data, velocityFields, tol, "first ", "second ")
+	case opSetRPM:
+		stepErr = checkSteps(res, des, data, rpmFields, tol)
+	case opConsecutiveRPM:
+		stepErr = checkSteps(res, des, data, rpmFields, tol, "first ", "second ")
+	}
+
+	var err error
+	switch step.Op {
+	case opSetVelocity:
+		err = replayVelocity(segments[0], res, "", step.Linear, step.Angular, tol)
+	case opConsecutiveVelocity:
+		if err = replayVelocity(segments[0], res, "first ", step.Linear, 0, tol); err == nil {
+			err = replayVelocity(segments[1], res, "second ", step.NextLinear, 0, tol)
+		}
+	case opMoveStraight:
+		err = replayMoveStraight(window(data, des), data, des[len(des)-1], res, step.Distance, step.Speed, profile, tol)
+	case opSpin:
+		err = replaySpin(window(data, des), data, des, res, step.Distance, step.Speed, step.TestSpeed, profile, tol)
+	case opGoFor:
+		err = replayGoFor(window(data, des), data, des[0], res, step.RPM, step.Revolutions, tol)
+	case opGoTo:
+		err = replayGoTo(window(data, des), data, des[0], res, step.RPM, step.Position, tol)
+	case opSetRPM:
+		err = replayRPM(segments[0], res, "", step.RPM, tol)
+	case opConsecutiveRPM:
+		if err = replayRPM(segments[0], res, "first ", step.RPM, tol); err == nil {
+			err = replayRPM(segments[1], res, "second ", step.NextRPM, tol)
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", step.Op)
+	}
+	if err == nil {
+		err = stepErr
+	}
+	res.finish(err)
+}
+
+// replayGrid re-judges the grid from its measured records, walking the plan's moves through the start marker of
+// the grid, the samples and end marker of every straight and arc and the markers of every spin, and finishing
+// res the way the live test would have.
+func replayGrid(plan gridPlan, header samples.Header, data []samples.Record, res *testResult) {
+	if header.Version < gridMarkersVersion {
+		res.skip(fmt.Sprintf("grid recorded with sample schema version %d, replaying it needs version %d or newer", header.Version, gridMarkersVersion))
+		return
+	}
+	moves, err := plan.moves()
+	if err != nil {
+		res.finish(err)
+		return
+	}
+
+	// next returns the next marker and the samples before it
+	var i int
+	next := func() (samples.Record, []pose, bool) {
+		var driven []pose
+		for ; i < len(data); i++ {
+			if data[i].Type != samples.Grid {
+				continue
+			}
+			if data[i].Marker == "" {
+				driven = append(driven, poseFromRecord(data[i]))
+				continue
+			}
+			i++
+			return data[i-1], driven, true
+		}
+		return samples.Record{}, nil, false
+	}
+	incomplete := func() { res.skip("incomplete recording for this test case") }
+
+	start, _, ok := next()
+	if !ok || start.Marker != samples.Start {
+		incomplete()
+		return
+	}
+	desired := poseFromRecord(start)
+	desPoses, poses := []pose{desired}, []pose{desired}
+	var legs []gridLeg
+	var turns []gridTurn
+	for _, m := range moves {
+		switch m.op {
+		case gridStraight, gridArc:
+			leg := gridLeg{from: desired, start: poses[len(poses)-1]}
+			if m.op == gridStraight {
+				leg.to = desired.moved(m.distance)
+			} else {
+				leg.to, leg.radius, leg.angle = desired.arced(m.radius, m.angle), m.radius, m.angle
+			}
+			endRow, driven, ok := next()
+			if !ok || endRow.Marker != samples.End {
+				incomplete()
+				return
+			}
+			leg.samples = driven
+			leg.end = poseFromRecord(endRow)
+			desired = leg.to
+			desPoses = append(desPoses, desired)
+			poses = append(poses, leg.end)
+			legs = append(legs, leg)
+
+		case gridSpin:
+			desired = desired.turned(m.angle)
+			spinStart, _, ok := next()
+			if !ok || spinStart.Marker != samples.Start {
+				incomplete()
+				return
+			}
+			spinEnd, _, ok := next()
+			if !ok || spinEnd.Marker != samples.End {
+				incomplete()
+				return
+			}
+			turn := gridTurn{planned: desired.theta, end: poses[len(poses)-1]}
+			turn.end.theta = spinEnd.Theta
+			turns = append(turns, turn)
+		}
+	}
+
+	metrics := analyzeGrid(legs, turns, poses[len(poses)-1], desired)
+	res.finish(checkGridRun(res, metrics, poses, desPoses))
+}
+
+func replayVelocity(window []samples.Record, res *testResult, prefix string, linear, angular float64, tol tolerance) error {
+	est, err := estimateWindow(window, tol.estimator, linear, angular, 0)
+	if err != nil {
+		return err
+	}
+	return checkVelocity(res, prefix, est.linear.estimate(), est.angular.estimate(), linear, angular, tol)
+}
+
+func replayMoveStraight(window, data []samples.Record, goal samples.Record, res *testResult, distance, speed float64, profile hardwareProfile, tol tolerance) error {
+	endRow, err := end(data)
+	if err != nil {
+		return err
+	}
+	odometryErr := replayOdometry(data, profile, res)
+	dir := sign(distance * speed)
+	est, err := estimateWindow(window, tol.estimator, math.Abs(speed)*dir, 0, 0)
+	if err != nil {
+		return err
+	}
+	// the last desired record holds the start pose moved by the requested distance
+	start := poseFromRecord(goal).moved(-math.Abs(distance) * dir)
+	if err := checkMoveStraight(res, start.progressTo(poseFromRecord(endRow)), est.linear.estimate(), distance, speed, tol); err != nil {
+		return err
+	}
+	return odometryErr
+}
+
+func replaySpin(window, data, des []samples.Record, res *testResult, distance, speed float64, testSpeed bool, profile hardwareProfile, tol tolerance) error {
+	if _, err := end(data); err != nil {
+		return err
+	}
+	odometryErr := replayOdometry(data, profile, res)
+	dir := sign(distance * speed)
+	est, err := estimateWindow(window, tol.estimator, 0, math.Abs(speed)*dir, 0)
+	if err != nil {
+		return err
+	}
+	// the heading is tracked from the start marker through every sample to the end marker
+	var heading headingTracker
+	for _, row := range data {
+		heading.add(row.Theta)
+	}
+	took := time.Duration(des[len(des)-1].TimeMs-des[0].TimeMs) * time.Millisecond
+	if err := checkSpin(res, heading.turned(), est.angular.estimate(), took, distance, speed, testSpeed, tol); err != nil {
+		return err
+	}
+	return odometryErr
+}
+
+func replayGoFor(window, data []samples.Record, start samples.Record, res *testResult, rpm, revolutions float64, tol tolerance) error {
+	endRow, err := end(data)
+	if err != nil {
+		return err
+	}
+	est, err := estimateWindow(window, tol.estimator, 0, 0, math.Abs(rpm)*sign(rpm*revolutions))
+	if err != nil {
+		return err
+	}
+	return checkGoFor(res, endRow.Position-start.Position, est.rpm.estimate(), rpm, revolutions, tol)
+}
+
+func replayGoTo(window, data []samples.Record, start samples.Record, res *testResult, rpm, position float64, tol tolerance) error {
+	endRow, err := end(data)
+	if err != nil {
+		return err
+	}
+	est, err := estimateWindow(window, tol.estimator, 0, 0, math.Abs(rpm)*sign((position-start.Position)*rpm))
+	if err != nil {
+		return err
+	}
+	return checkGoTo(res, start.Position, endRow.Position, est.rpm.estimate(), rpm, position, tol)
+}
+
+func replayRPM(window []samples.Record, res *testResult, prefix string, rpm float64, tol tolerance) error {
+	est, err := estimateWindow(window, tol.estimator, 0, 0, rpm)
+	if err != nil {
+		return err
+	}
+	return checkRPM(res, prefix, est.rpm.estimate(), rpm, tol)
+}
+
+// estimateWindow feeds the measured records of a window to the named estimators for the given goals.
+func estimateWindow(window []samples.Record, name string, linear, angular, rpm float64) (*speedEstimates, error) {
+	est, err := newSpeedEstimates(name, linear, angular, rpm)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range window {
+		est.add(row)
+	}
+	return est, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runReport is the machine-readable summary of a canary run.
+type runReport struct {
+	Start   time.Time       `json:"start"`
+	End     time.Time       `json:"end"`
+	Profile hardwareProfile `json:"hardware_profile"`
+	Env     runEnvironment  `json:"environment"`
+	Passed  bool            `json:"passed"`
+	Summary resultSummary   `json:"summary"`
+	Results []testResult    `json:"results"`
+}
+
+// newRunReport builds the report for a run from the collected results.
+func newRunReport(start, end time.Time, profile hardwareProfile, env runEnvironment, collector *resultCollector) runReport {
+	summary := collector.summary()
+	return runReport{
+		Start:   start,
+		End:     end,
+		Profile: profile,
+		Env:     env,
+		Passed:  summary.unsuccessful() == 0,
+		Summary: summary,
+		Results: collector.all(),
+	}
+}
+
+// junit report structure, see https://llg.cubic.org/docs/junit/
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       float64         `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// junit converts the report into junit xml with one testsuite per suite, in the order each suite first ran.
+func (r runReport) junit() junitTestSuites {
+	out := junitTestSuites{
+		Name: "rover-canary",
+		Time: r.End.Sub(r.Start).Seconds(),
+	}
+	index := map[string]int{}
+	for _, res := range r.Results {
+		i, ok := index[res.Suite]
+		if !ok {
+			i = len(out.Suites)
+			index[res.Suite] = i
+			out.Suites = append(out.Suites, junitTestSuite{
+				Name:       res.Suite,
+				Timestamp:  r.Start.Format("2006-01-02T15:04:05"),
+				Properties: r.properties(),
+			})
+		}
+		suite := &out.Suites[i]
+
+		tc := junitTestCase{
+			Name:      res.Name,
+			Classname: "rovercanary." + strings.ReplaceAll(res.Suite, " ", "_"),
+			Time:      res.Duration.Seconds(),
+			SystemOut: formatMeasurements(res.Measurements, res.SkippedChecks),
+		}
+		if res.Component != "" {
+			tc.Classname += "." + res.Component
+		}
+		switch res.Status {
+		case statusFail:
+			tc.Failure = &junitMessage{Message: res.Message, Body: tc.SystemOut}
+			suite.Failures++
+		case statusError, statusTimeout:
+			tc.Error = &junitMessage{Message: res.Message, Type: string(res.Status)}
+			suite.Errors++
+		case statusSkip:
+			tc.Skipped = &junitMessage{Message: res.Message}
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.Time += tc.Time
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	for _, suite := range out.Suites {
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Errors += suite.Errors
+		out.Skipped += suite.Skipped
+	}
+	return out
+}
+
+// properties are the junit properties every testsuite is labelled with: the environment the run ran in.
+func (r runReport) properties() []junitProperty {
+	var props []junitProperty
+	for _, l := range r.Env.labels() {
+		props = append(props, junitProperty{Name: l.name, Value: l.value})
+	}
+	return append(props, junitProperty{Name: "resources", Value: strings.Join(r.Env.Resources, ",")})
+}
+
+// formatMeasurements writes one line per measurement, marking the ones out of tolerance, then one per skipped
+// check.
+func formatMeasurements(measurements []measurement, skipped []string) string {
+	var sb strings.Builder
+	for _, m := range measurements {
+		mark := "ok"
+		if !m.Passed {
+			mark = "FAIL"
+		}
+		fmt.Fprintf(&sb, "%v: measured %v, expected %v +/- %v [%v]\n", m.Name, m.Measured, m.Expected, m.Tolerance, mark)
+	}
+	for _, check := range skipped {
+		fmt.Fprintf(&sb, "%v\n", check)
+	}
+	return sb.String()
+}
+
+// writeReports writes the junit xml and json summary for a run into dir and returns their paths. The files are
+// named after the run ID, which is unique even for runs started in the same second.
+func writeReports(dir, runID string, report runReport) (string, string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+
+	junitPath := filepath.Join(dir, fmt.Sprintf("junit-%v.xml", runID))
+	junitXML, err := xml.MarshalIndent(report.junit(), "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(junitPath, append([]byte(xml.Header), junitXML...), 0o644); err != nil {
+		return "", "", err
+	}
+
+	jsonPath := filepath.Join(dir, fmt.Sprintf("summary-%v.json", runID))
+	summaryJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(jsonPath, summaryJSON, 0o644); err != nil {
+		return "", "", err
+	}
+	return junitPath, jsonPath, nil
+}
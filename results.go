@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// testStatus is the outcome of a single test.
+type testStatus string
+
+const (
+	statusPass    testStatus = "pass"
+	statusFail    testStatus = "fail"    // the test ran but a measurement was out of tolerance
+	statusError   testStatus = "error"   // the test could not complete, usually because of an rpc error
+	statusTimeout testStatus = "timeout" // the test did not finish before its deadline
+	statusSkip    testStatus = "skip"    // the test did not run
+)
+
+// suites group results in reports, one per component under test
+const (
+	suiteSetup           = "setup"
+	suitePreflight       = "preflight"
+	suiteWheeledBase     = "wheeled base"
+	suiteSensorBase      = "sensor base"
+	suiteEncodedMotor    = "encoded motor"
+	suiteControlledMotor = "controlled motor"
+	suiteEncoder         = "encoder"
+	suitePowerSensor     = "power sensor"
+	suiteMovementSensor  = "movement sensor"
+	suiteGrid            = "grid"
+)
+
+// measurement is a single measured value checked against its expected value.
+type measurement struct {
+	Name      string  `json:"name"`
+	Measured  float64 `json:"measured"`
+	Expected  float64 `json:"expected"`
+	Tolerance float64 `json:"tolerance"`
+	Passed    bool    `json:"passed"`
+}
+
+// testResult records everything known about one test once it has finished.
+type testResult struct {
+	Name         string             `json:"name"`
+	Suite        string             `json:"suite"`
+	Component    string             `json:"component"`
+	Operation    string             `json:"operation"`
+	Params       map[string]float64 `json:"params,omitempty"`
+	Case         string             `json:"case,omitempty"`      // test case id its samples are recorded under
+	Estimator    string             `json:"estimator,omitempty"` // how the measured speed was estimated
+	Status       testStatus         `json:"status"`
+	Measurements []measurement      `json:"measurements,omitempty"`
+	Duration     time.Duration      `json:"duration_ns"`
+	Err          error              `json:"-"`
+	Message      string             `json:"error,omitempty"`
+	// SkippedChecks are the checks of a test that could not run, each with the reason
+	SkippedChecks []string `json:"skipped_checks,omitempty"`
+
+	start time.Time
+}
+
+// newResult starts a result for the given component and operation in a suite. The name is derived from the
+// operation and its params.
+func newResult(suite, component, operation string, params map[string]float64) *testResult {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	name := operation
+	for _, key := range keys {
+		name += fmt.Sprintf(" %v=%v", key, params[key])
+	}
+
+	return &testResult{
+		Name:      name,
+		Suite:     suite,
+		Component: component,
+		Operation: operation,
+		Params:    params,
+		start:     time.Now(),
+	}
+}
+
+// check records a measurement and reports whether it is within tolerance of the expected value.
+func (r *testResult) check(name string, measured, expected, tolerance float64) bool {
+	passed := math.Abs(measured-expected) <= tolerance
+	r.Measurements = append(r.Measurements, measurement{
+		Name:      name,
+		Measured:  measured,
+		Expected:  expected,
+		Tolerance: tolerance,
+		Passed:    passed,
+	})
+	return passed
+}
+
+// checkMin records a measurement that passes when it is at least the minimum value.
+func (r *testResult) checkMin(name string, measured, minimum float64) bool {
+	passed := measured >= minimum
+	r.Measurements = append(r.Measurements, measurement{
+		Name:     name,
+		Measured: measured,
+		Expected: minimum,
+		Passed:   passed,
+	})
+	return passed
+}
+
+// checkMax records a measurement that passes when it is at most the maximum value.
+func (r *testResult) checkMax(name string, measured, maximum float64) bool {
+	passed := measured <= maximum
+	r.Measurements = append(r.Measurements, measurement{
+		Name:     name,
+		Measured: measured,
+		Expected: maximum,
+		Passed:   passed,
+	})
+	return passed
+}
+
+// missing records a measurement that could not be taken, which fails.
+func (r *testResult) missing(name string, expected float64) {
+	r.Measurements = append(r.Measurements, measurement{
+		Name:     name,
+		Expected: expected,
+	})
+}
+
+// finish sets the duration and status of the result. A deadline error makes the test a timeout, then any
+// failed measurement makes it a failure, otherwise a non-nil error makes it an error.
+func (r *testResult) finish(err error) {
+	r.Duration = time.Since(r.start)
+	r.Err = err
+	if err != nil {
+		r.Message = err.Error()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		r.Status = statusTimeout
+		return
+	}
+	r.Status = statusPass
+	for _, m := range r.Measurements {
+		if !m.Passed {
+			r.Status = statusFail
+			return
+		}
+	}
+	if err != nil {
+		r.Status = statusError
+	}
+}
+
+// caseID identifies the index'th step of a suite's plan in its sample files.
+func caseID(index int, op string) string {
+	return fmt.Sprintf("%02d-%v", index+1, op)
+}
+
+// skip marks the result as skipped for the given reason.
+func (r *testResult) skip(reason string) {
+	r.Status = statusSkip
+	r.Message = reason
+}
+
+// skipCheck notes that one check of the test was skipped for the given reason, the test is still judged on
+// the rest.
+func (r *testResult) skipCheck(check, reason string) {
+	r.SkippedChecks = append(r.SkippedChecks, fmt.Sprintf("%v skipped: %v", check, reason))
+}
+
+// resultSummary counts results by status. Total only includes tests that ran.
+type resultSummary struct {
+	Total    int `json:"total"`
+	Passed   int `json:"passed"`
+	Failed   int `json:"failed"`
+	Errored  int `json:"errored"`
+	TimedOut int `json:"timed_out"`
+	Skipped  int `json:"skipped"`
+}
+
+// unsuccessful is the number of tests that ran and did not pass.
+func (s resultSummary) unsuccessful() int {
+	return s.Failed + s.Errored + s.TimedOut
+}
+
+// resultCollector gathers results from every suite in a run.
+type resultCollector struct {
+	mu      sync.Mutex
+	results []*testResult
+}
+
+func (c *resultCollector) add(r *testResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, r)
+}
+
+// all returns a copy of every result in the order they were added.
+func (c *resultCollector) all() []testResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]testResult, 0, len(c.results))
+	for _, r := range c.results {
+		out = append(out, *r)
+	}
+	return out
+}
+
+func (c *resultCollector) summary() resultSummary {
+	var s resultSummary
+	for _, r := range c.all() {
+		switch r.Status {
+		case statusPass:
+			s.Passed++
+		case statusFail:
+			s.Failed++
+		case statusError:
+			s.Errored++
+		case statusTimeout:
+			s.TimedOut++
+		case statusSkip:
+			s.Skipped++
+		}
+	}
+	s.Total = s.Passed + s.unsuccessful()
+	return s
+}
+
+// slackMessage builds the slack summary of unsuccessful tests and failed pre-flight checks, or returns an
+// empty string if there were none.
+func (c *resultCollector) slackMessage(env runEnvironment) string {
+	s := c.summary()
+	var preconditions []testResult
+	for _, r := range c.all() {
+		if r.Suite == suitePreflight && r.Status == statusSkip {
+			preconditions = append(preconditions, r)
+		}
+	}
+	if s.unsuccessful() == 0 && len(preconditions) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	if s.unsuccessful() != 0 {
+		fmt.Fprintf(&sb, "tests failed (%v): %d/%d\n", env.Profile, s.unsuccessful(), s.Total)
+	} else {
+		fmt.Fprintf(&sb, "motion tests skipped (%v): pre-flight failed\n", env.Profile)
+	}
+	fmt.Fprintf(&sb, "viam-server %v, canary %v on %v/%v\n", env.ServerVersion, env.CanaryRevision, env.OS, env.Arch)
+	for _, r := range c.all() {
+		if r.Status != statusPass && r.Status != statusSkip {
+			fmt.Fprintf(&sb, "- %v %v [%v]: %v\n", r.Component, r.Name, r.Status, r.Message)
+		}
+	}
+	for _, r := range preconditions {
+		fmt.Fprintf(&sb, "- %v %v [%v]: %v\n", r.Component, r.Name, r.Status, r.Message)
+	}
+	if s.Skipped != 0 {
+		fmt.Fprintf(&sb, "skipped: %d\n", s.Skipped)
+	}
+	return sb.String()
+}
+
+// runWithTimeout runs a test under a context derived from ctx that expires after timeout. When the deadline
+// is hit the returned error wraps context.DeadlineExceeded so the result is reported as a timeout.
+func runWithTimeout(ctx context.Context, timeout time.Duration, test func(context.Context) error) error {
+	testCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := test(testCtx)
+	if errors.Is(testCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: test did not finish within %v, err = %v", context.DeadlineExceeded, timeout, err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/robot"
+)
+
+// Runner owns everything a single canary run needs: the robot, its config, plan and hardware profile, the clock
+// samples are timed against, where sample files are written and the collected results. Runners share no state,
+// so several runs, for example against two rovers, can run concurrently in one process.
+type Runner struct {
+	logger  logging.Logger
+	machine robot.Robot
+	cfg     canaryConfig
+	plan    testPlan
+	profile hardwareProfile
+
+	// outDir is the run directory every sample file and plot is written to, id is its name.
+	outDir   string
+	id       string
+	results  *resultCollector
+	safety   *safetySupervisor
+	posExtra map[string]interface{}
+	start    time.Time
+	env      runEnvironment
+	// encoders are the wheel encoders the odometry is checked against during base motions
+	encoders wheelEncoders
+
+	// configChanges are the machine config changes since prevRun, the last run with a config snapshot.
+	configChanges []configChange
+	prevRun       string
+}
+
+// newRunner creates a runner for one canary run against machine.
+func newRunner(logger logging.Logger, machine robot.Robot, cfg canaryConfig, plan testPlan, profile hardwareProfile, outDir string) *Runner {
+	return &Runner{
+		logger:   logger,
+		machine:  machine,
+		cfg:      cfg,
+		plan:     plan,
+		profile:  profile,
+		outDir:   outDir,
+		id:       filepath.Base(outDir),
+		results:  &resultCollector{},
+		safety:   newSafetySupervisor(logger, stopTimeout),
+		posExtra: map[string]interface{}{"return_relative_pos_m": true},
+		start:    time.Now(),
+	}
+}
+
+// elapsed is the time since the run started, used to timestamp samples.
+func (r *Runner) elapsed() time.Duration {
+	return time.Since(r.start)
+}
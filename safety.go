@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+// stopTimeout bounds how long the supervisor waits for every actuator to stop.
+const stopTimeout = 5 * time.Second
+
+// stopper is an actuator the canary can stop, both bases and motors satisfy it.
+type stopper interface {
+	Name() resource.Name
+	Stop(ctx context.Context, extra map[string]interface{}) error
+}
+
+// safetySupervisor tracks every actuator touched by a run so they can all be stopped when a test
+// returns an error, the canary panics or the process is interrupted.
+type safetySupervisor struct {
+	logger    logging.Logger
+	mu        sync.Mutex
+	actuators map[resource.Name]stopper
+	timeout   time.Duration
+}
+
+func newSafetySupervisor(logger logging.Logger, timeout time.Duration) *safetySupervisor {
+	return &safetySupervisor{
+		logger:    logger,
+		actuators: map[resource.Name]stopper{},
+		timeout:   timeout,
+	}
+}
+
+// track adds an actuator to the set stopped by stopAll.
+func (s *safetySupervisor) track(actuator stopper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actuators[actuator.Name()] = actuator
+}
+
+// stopAll stops every tracked actuator concurrently, waiting at most the supervisor's timeout, and logs the outcome.
+func (s *safetySupervisor) stopAll(reason string) error {
+	s.mu.Lock()
+	actuators := make([]stopper, 0, len(s.actuators))
+	for _, actuator := range s.actuators {
+		actuators = append(actuators, actuator)
+	}
+	s.mu.Unlock()
+
+	if len(actuators) == 0 {
+		return nil
+	}
+	s.logger.Warnf("stopping %d actuators (%v)", len(actuators), reason)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs error
+	)
+	for _, actuator := range actuators {
+		wg.Add(1)
+		go func(actuator stopper) {
+			defer wg.Done()
+			if err := actuator.Stop(ctx, nil); err != nil {
+				s.logger.Errorf("error stopping %v, err = %v", actuator.Name().ShortName(), err)
+				mu.Lock()
+				errs = multierr.Combine(errs, err)
+				mu.Unlock()
+				return
+			}
+			s.logger.Infof("stopped %v", actuator.Name().ShortName())
+		}(actuator)
+	}
+	wg.Wait()
+	return errs
+}
+
+// recoverPanic stops every tracked actuator and panics again when the goroutine deferring it panics. The main
+// goroutine and every goroutine started during a run defer it, so a panic anywhere leaves the rover stopped.
+func (s *safetySupervisor) recoverPanic() {
+	if p := recover(); p != nil {
+		s.stopAll(fmt.Sprintf("panic: %v", p))
+		panic(p)
+	}
+}
+
+// signalExitCode is the conventional exit status of a process ended by sig, 128 plus the signal number: 130 for
+// SIGINT and 143 for SIGTERM.
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// watchSignals stops every tracked actuator and exits when the process receives SIGINT or SIGTERM.
+// The returned function stops watching.
+func (s *safetySupervisor) watchSignals() func() {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigs:
+			if err := s.stopAll("received " + sig.String()); err != nil {
+				s.logger.Errorf("not every actuator stopped before exit, err = %v", err)
+			}
+			os.Exit(signalExitCode(sig))
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
@@ -0,0 +1,261 @@
+// Package samples reads and writes the sample files a canary run records, one desired and one measured file
+// per suite.
+//
+// A sample file is JSON Lines. The first line is a Header naming the schema, its version, the suite, whether
+// the file holds desired or measured values and the unit of every Record field. Every following line is a
+// Record: the test case it belongs to, its type, the time it was taken and the values for that type, at full
+// precision. Fields that do not apply to a type, and fields that are zero, are omitted.
+//
+// Base records (set velocity, move straight, spin, grid) use the velocity, x, y and theta fields. Motor
+// records (go for, go to, set rpm) use the rpm and position fields. Rows that mark where a motion started or
+// ended rather than a sample taken during it have a marker.
+//
+// Move straight and spin records marked encoders are readings of the wheel encoders taken together with the
+// odometry's position and heading, the left and right ticks fields holding each encoder's position in ticks.
+// Version 1 files have none.
+//
+// Grid files start with a start marker holding the pose the grid started at, and every straight or arc of the
+// grid ends with an end marker holding the position the base ended it at, so a replay can split the samples into
+// the grid's moves. Version 2 and older files have none.
+package samples
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// SchemaName identifies a sample file.
+	SchemaName = "rover-canary-samples"
+	// Version is the schema version written, readers accept this version and older.
+	Version = 3
+)
+
+// Kind says whether a file holds the values a test asked for or the values it measured.
+type Kind string
+
+// kinds of sample file
+const (
+	Desired  Kind = "desired"
+	Measured Kind = "measured"
+)
+
+// Type is the operation a record was taken during.
+type Type string
+
+// record types
+const (
+	SetVelocity  Type = "sv"
+	MoveStraight Type = "ms"
+	Spin         Type = "s"
+	GoFor        Type = "gf"
+	GoTo         Type = "gt"
+	SetRPM       Type = "rpm"
+	Grid         Type = "grid"
+)
+
+// Marker labels a record that is not a sample taken during a motion.
+type Marker string
+
+// markers
+const (
+	Start    Marker = "start"    // where a motion started, written before it
+	End      Marker = "end"      // where a motion ended, written after it
+	Encoders Marker = "encoders" // a reading of the wheel encoders and the odometry taken together
+)
+
+// Units maps every Record field to its unit.
+var Units = map[string]string{
+	"time_ms":          "ms since the run started",
+	"linear_velocity":  "mm/sec",
+	"angular_velocity": "deg/sec",
+	"x":                "m, odometry position relative to its last reset",
+	"y":                "m, odometry position relative to its last reset",
+	"theta":            "rad",
+	"rpm":              "rev/min",
+	"position":         "rev",
+	"prev_position":    "rev",
+	"left_ticks":       "ticks, left wheel encoder position",
+	"right_ticks":      "ticks, right wheel encoder position",
+}
+
+// Header is the first line of a sample file.
+type Header struct {
+	Schema  string            `json:"schema"`
+	Version int               `json:"version"`
+	RunID   string            `json:"run_id,omitempty"`
+	Suite   string            `json:"suite"`
+	Kind    Kind              `json:"kind"`
+	Units   map[string]string `json:"units"`
+}
+
+// Record is a single desired or measured value of a test case.
+type Record struct {
+	Case   string `json:"case"`
+	Type   Type   `json:"type"`
+	Marker Marker `json:"marker,omitempty"`
+	TimeMs int64  `json:"time_ms"`
+
+	// base fields
+	LinearVelocity  float64 `json:"linear_velocity,omitempty"`
+	AngularVelocity float64 `json:"angular_velocity,omitempty"`
+	X               float64 `json:"x,omitempty"`
+	Y               float64 `json:"y,omitempty"`
+	Theta           float64 `json:"theta,omitempty"`
+	LeftTicks       float64 `json:"left_ticks,omitempty"`
+	RightTicks      float64 `json:"right_ticks,omitempty"`
+
+	// motor fields
+	RPM          float64 `json:"rpm,omitempty"`
+	Position     float64 `json:"position,omitempty"`
+	PrevPosition float64 `json:"prev_position,omitempty"`
+}
+
+// Writer writes records to a sample file, tagging each with the current test case. It is safe for concurrent use.
+type Writer struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	caseID    string
+	observers map[int]func(Record)
+	nextID    int
+}
+
+// NewWriter writes the header for a file of the given kind to w and returns a writer for its records.
+func NewWriter(w io.Writer, runID, suite string, kind Kind) (*Writer, error) {
+	enc := json.NewEncoder(w)
+	header := Header{
+		Schema:  SchemaName,
+		Version: Version,
+		RunID:   runID,
+		Suite:   suite,
+		Kind:    kind,
+		Units:   Units,
+	}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return &Writer{enc: enc}, nil
+}
+
+// SetCase sets the test case ID records without one are tagged with.
+func (w *Writer) SetCase(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.caseID = id
+}
+
+// Observe passes every record written to observer, tagged with its case, until the returned function is called.
+func (w *Writer) Observe(observer func(Record)) (stop func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.observers == nil {
+		w.observers = map[int]func(Record){}
+	}
+	id := w.nextID
+	w.nextID++
+	w.observers[id] = observer
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.observers, id)
+	}
+}
+
+// Write writes a record.
+func (w *Writer) Write(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if r.Case == "" {
+		r.Case = w.caseID
+	}
+	for _, observer := range w.observers {
+		observer(r)
+	}
+	return w.enc.Encode(r)
+}
+
+// Reader reads the records of a sample file.
+type Reader struct {
+	Header  Header
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader reads and checks the header of the sample file in r.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{scanner: bufio.NewScanner(r)}
+	if !reader.scanner.Scan() {
+		if err := reader.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("missing header")
+	}
+	reader.line++
+	if err := json.Unmarshal(reader.scanner.Bytes(), &reader.Header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if reader.Header.Schema != SchemaName {
+		return nil, fmt.Errorf("schema %q is not %q", reader.Header.Schema, SchemaName)
+	}
+	if reader.Header.Version > Version {
+		return nil, fmt.Errorf("schema version %d is newer than %d", reader.Header.Version, Version)
+	}
+	return reader, nil
+}
+
+// Read returns the next record, or io.EOF when there are none left.
+func (r *Reader) Read() (Record, error) {
+	var rec Record
+	for r.scanner.Scan() {
+		r.line++
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(r.scanner.Bytes(), &rec); err != nil {
+			return rec, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return rec, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return rec, err
+	}
+	return rec, io.EOF
+}
+
+// ReadAll reads the header and every record of the sample file in r.
+func ReadAll(r io.Reader) (Header, []Record, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	var records []Record
+	for {
+		rec, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return reader.Header, records, nil
+		}
+		if err != nil {
+			return reader.Header, records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// ReadFile reads the header and every record of the sample file at path.
+func ReadFile(path string) (Header, []Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	defer f.Close()
+	header, records, err := ReadAll(f)
+	if err != nil {
+		return header, records, fmt.Errorf("%v: %w", path, err)
+	}
+	return header, records, nil
+}
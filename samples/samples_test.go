@@ -0,0 +1,75 @@
+package samples
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var out strings.Builder
+	w, err := NewWriter(&out, "20240301-123000", "wheeled base", Measured)
+	test.That(t, err, test.ShouldBeNil)
+
+	w.SetCase("03-move_straight")
+	written := []Record{
+		{Type: MoveStraight, Marker: Start, TimeMs: 1200},
+		{Type: MoveStraight, TimeMs: 1250, LinearVelocity: 199.87654321, X: 0.000123456789, Theta: -0.5},
+		{Type: MoveStraight, Marker: Encoders, TimeMs: 1260, X: 0.0002, Theta: -0.5, LeftTicks: 1992.5, RightTicks: -3.25},
+		{Case: "04-go_for", Type: GoFor, TimeMs: 1300, RPM: 59.999, Position: 7.9999999, PrevPosition: 7.5},
+	}
+	for _, rec := range written {
+		test.That(t, w.Write(rec), test.ShouldBeNil)
+	}
+
+	header, records, err := ReadAll(strings.NewReader(out.String()))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, header, test.ShouldResemble, Header{
+		Schema:  SchemaName,
+		Version: Version,
+		RunID:   "20240301-123000",
+		Suite:   "wheeled base",
+		Kind:    Measured,
+		Units:   Units,
+	})
+	written[0].Case, written[1].Case, written[2].Case = "03-move_straight", "03-move_straight", "03-move_straight"
+	test.That(t, records, test.ShouldResemble, written)
+}
+
+func TestReaderRejects(t *testing.T) {
+	for name, file := range map[string]string{
+		"empty":          "",
+		"not a header":   `{"case":"01-spin","type":"s","time_ms":1}`,
+		"newer version":  `{"schema":"rover-canary-samples","version":99}`,
+		"invalid record": "{\"schema\":\"rover-canary-samples\",\"version\":1}\nnot json\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(file))
+			if err == nil {
+				_, err = r.Read()
+				test.That(t, err, test.ShouldNotEqual, io.EOF)
+			}
+			test.That(t, err, test.ShouldNotBeNil)
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	w, err := NewWriter(io.Discard, "20240301-123000", "grid", Measured)
+	test.That(t, err, test.ShouldBeNil)
+	w.SetCase("grid")
+
+	var first, second []Record
+	stopFirst := w.Observe(func(r Record) { first = append(first, r) })
+	stopSecond := w.Observe(func(r Record) { second = append(second, r) })
+	test.That(t, w.Write(Record{Type: Grid, TimeMs: 1}), test.ShouldBeNil)
+	stopFirst()
+	test.That(t, w.Write(Record{Type: Grid, TimeMs: 2}), test.ShouldBeNil)
+	stopSecond()
+	test.That(t, w.Write(Record{Type: Grid, TimeMs: 3}), test.ShouldBeNil)
+
+	test.That(t, first, test.ShouldResemble, []Record{{Case: "grid", Type: Grid, TimeMs: 1}})
+	test.That(t, second, test.ShouldResemble, []Record{{Case: "grid", Type: Grid, TimeMs: 1}, {Case: "grid", Type: Grid, TimeMs: 2}})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+
+	"go.viam.com/rdk/resource"
+)
+
+// fake motor settings, max rpm must cover the fastest rpm in the plan
+const (
+	simulatedTicksPerRotation = 1992
+	simulatedMaxRPM           = 200
+)
+
+// simulatedComponents are the stock rover names used when simulating without a components section.
+var simulatedComponents = componentNames{
+	WheeledBase:    "viam_base",
+	SensorBase:     "sensor_base",
+	LeftMotor:      "left",
+	RightMotor:     "right",
+	LeftEncoder:    "left-enc",
+	RightEncoder:   "right-enc",
+	Odometry:       "odometry",
+	PowerSensor:    "ina219",
+	MovementSensor: "imu",
+}
+
+var fakeModel = resource.DefaultModelFamily.WithModel("fake")
+
+// profileSimulated names simulatedProfile, which is not in hardwareProfiles so a rover can not be judged by it.
+const profileSimulated = "simulated"
+
+// simulatedProfile is the hardware profile of every simulated run. Its wheels are a v2's and its nominal
+// readings are the rdk fake power sensor's and movement sensor's.
+var simulatedProfile = hardwareProfile{
+	Name:               profileSimulated,
+	TicksPerRotation:   simulatedTicksPerRotation,
+	WheelCircumference: 381.0,
+	WheelBase:          356.0,
+	Voltage:            1.5,
+	VoltageTolerance:   0.1,
+	Current:            2.2,
+	CurrentTolerance:   0.1,
+	Power:              9.8,
+	PowerTolerance:     0.1,
+	Gravity:            2,
+}
+
+// loadSimulatedConfig reads the config file at path if it exists, keeping its plan, components and thresholds.
+// Credentials are not needed and the webhook is cleared so a simulated run never reaches the network. The
+// profile is always the simulated one, the fake components read nothing else.
+func loadSimulatedConfig(path string) (canaryConfig, error) {
+	if _, err := os.Stat(path); err != nil {
+		path = ""
+	}
+	cfg, err := readConfig(path)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Webhook = ""
+	cfg.Profile = profileSimulated
+	if cfg.Components == (componentNames{}) {
+		cfg.Components = simulatedComponents
+	}
+	return cfg, nil
+}
@@ -0,0 +1,436 @@
+//go:build simulate
+
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/rdk/components/base"
+	// register the fake models used by --simulate
+	_ "go.viam.com/rdk/components/base/fake"
+	"go.viam.com/rdk/components/encoder"
+	_ "go.viam.com/rdk/components/encoder/fake"
+	"go.viam.com/rdk/components/motor"
+	_ "go.viam.com/rdk/components/motor/fake"
+	"go.viam.com/rdk/components/movementsensor"
+	_ "go.viam.com/rdk/components/movementsensor/fake"
+	"go.viam.com/rdk/components/powersensor"
+	_ "go.viam.com/rdk/components/powersensor/fake"
+	"go.viam.com/rdk/config"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/robot"
+	robotimpl "go.viam.com/rdk/robot/impl"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+	"go.viam.com/utils"
+)
+
+// newSimulatedRobot starts an in-process robot with an rdk fake component for every configured role. The bases,
+// motors, encoders and odometry are backed by one simulated rover, so what the bases and motors are told to do
+// turns its wheels, the encoders count the wheels and the odometry integrates them.
+func newSimulatedRobot(ctx context.Context, logger logging.Logger, names componentNames) (robot.LocalRobot, error) {
+	var components []resource.Config
+	added := map[string]bool{}
+	add := func(api resource.API, name string, attrs rdkutils.AttributeMap) {
+		// the odometry and movement sensor roles may share a sensor
+		if name == "" || added[name] {
+			return
+		}
+		added[name] = true
+		components = append(components, resource.Config{
+			Name:       name,
+			API:        api,
+			Model:      fakeModel,
+			Attributes: attrs,
+		})
+	}
+
+	add(encoder.API, names.LeftEncoder, nil)
+	add(encoder.API, names.RightEncoder, nil)
+	add(motor.API, names.LeftMotor, fakeMotorAttributes(names.LeftEncoder))
+	add(motor.API, names.RightMotor, fakeMotorAttributes(names.RightEncoder))
+	add(base.API, names.WheeledBase, nil)
+	add(base.API, names.SensorBase, nil)
+	add(movementsensor.API, names.Odometry, nil)
+	add(movementsensor.API, names.MovementSensor, nil)
+	add(powersensor.API, names.PowerSensor, nil)
+
+	// processing converts each component's attributes into its model's config
+	cfg := &config.Config{Components: components}
+	if err := cfg.ProcessLocal(logger); err != nil {
+		return nil, err
+	}
+	local, err := robotimpl.New(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &simulatedRobot{LocalRobot: local, resources: map[resource.Name]resource.Resource{}}
+	rover := newSimulatedRover(simulatedProfile)
+	// wrap finds a fake component and replaces it with its simulated version
+	wrap := func(name resource.Name, simulate func(resource.Resource) resource.Resource) error {
+		if name.Name == "" {
+			return nil
+		}
+		res, err := local.ResourceByName(name)
+		if err != nil {
+			return err
+		}
+		sim.resources[name] = simulate(res)
+		return nil
+	}
+	var errs []error
+	for _, name := range []string{names.WheeledBase, names.SensorBase} {
+		errs = append(errs, wrap(base.Named(name), func(res resource.Resource) resource.Resource {
+			return &simulatedBase{Base: res.(base.Base), rover: rover}
+		}))
+	}
+	for wheel, name := range []string{names.LeftMotor, names.RightMotor} {
+		wheel := wheel
+		errs = append(errs, wrap(motor.Named(name), func(res resource.Resource) resource.Resource {
+			return &simulatedMotor{Motor: res.(motor.Motor), rover: rover, wheel: wheel}
+		}))
+	}
+	for wheel, name := range []string{names.LeftEncoder, names.RightEncoder} {
+		wheel := wheel
+		errs = append(errs, wrap(encoder.Named(name), func(res resource.Resource) resource.Resource {
+			return &simulatedEncoder{Encoder: res.(encoder.Encoder), rover: rover, wheel: wheel}
+		}))
+	}
+	errs = append(errs, wrap(movementsensor.Named(names.Odometry), func(res resource.Resource) resource.Resource {
+		return &simulatedOdometry{MovementSensor: res.(movementsensor.MovementSensor), rover: rover}
+	}))
+	for _, err := range errs {
+		if err != nil {
+			local.Close(ctx)
+			return nil, err
+		}
+	}
+	return sim, nil
+}
+
+// fakeMotorAttributes configures a fake motor, backed by the named fake encoder when there is one.
+func fakeMotorAttributes(encoderName string) rdkutils.AttributeMap {
+	attrs := rdkutils.AttributeMap{"max_rpm": simulatedMaxRPM}
+	if encoderName != "" {
+		attrs["encoder"] = encoderName
+		attrs["ticks_per_rotation"] = simulatedTicksPerRotation
+	}
+	return attrs
+}
+
+// simulatedRobot is the local robot of fake components, serving the simulated version of the ones that move.
+type simulatedRobot struct {
+	robot.LocalRobot
+	resources map[resource.Name]resource.Resource
+}
+
+func (s *simulatedRobot) ResourceByName(name resource.Name) (resource.Resource, error) {
+	if res, ok := s.resources[name]; ok {
+		return res, nil
+	}
+	return s.LocalRobot.ResourceByName(name)
+}
+
+// wheels of the simulated rover
+const (
+	leftWheel = iota
+	rightWheel
+)
+
+// simulatedRover is a differential drive rover with the wheels of a hardware profile. Each wheel turns at the
+// rpm it was last set to; its revolutions and the rover's pose are integrated from those rpms whenever they
+// change or are read.
+type simulatedRover struct {
+	profile hardwareProfile
+
+	mu    sync.Mutex
+	since time.Time
+	rpm   [2]float64
+	power [2]float64 // fraction of full power, signed
+	revs  [2]float64 // at since
+	pose  pose       // at since, since the odometry was last reset
+	// revolutions each encoder was last reset at and the offset each motor position was last reset to
+	encoderZero [2]float64
+	motorOffset [2]float64
+}
+
+func newSimulatedRover(profile hardwareProfile) *simulatedRover {
+	return &simulatedRover{profile: profile, since: time.Now()}
+}
+
+// advance integrates the wheels and pose up to now. The caller holds mu.
+func (s *simulatedRover) advance() {
+	now := time.Now()
+	dt := now.Sub(s.since).Seconds()
+	s.since = now
+	linear, angular := s.velocity()
+	for wheel := range s.rpm {
+		s.revs[wheel] += s.rpm[wheel] / 60 * dt
+	}
+
+	// the rover drives an arc at constant linear and angular velocity, or a line when it is not turning
+	theta := s.pose.theta
+	if math.Abs(angular) < 1e-9 {
+		s.pose.point = s.pose.point.Add(r3.Vector{X: math.Cos(theta), Y: math.Sin(theta)}.Mul(linear * dt))
+		return
+	}
+	next := theta + angular*dt
+	radius := linear / angular
+	s.pose.point = s.pose.point.Add(r3.Vector{X: radius * (math.Sin(next) - math.Sin(theta)), Y: -radius * (math.Cos(next) - math.Cos(theta))})
+	s.pose.theta = wrapAngle(next)
+}
+
+// velocity is the rover's linear (mm/sec) and angular (rad/sec, counterclockwise) velocity from its wheels. The
+// caller holds mu.
+func (s *simulatedRover) velocity() (float64, float64) {
+	left := s.rpm[leftWheel] / 60 * s.profile.WheelCircumference
+	right := s.rpm[rightWheel] / 60 * s.profile.WheelCircumference
+	return (left + right) / 2, (right - left) / s.profile.WheelBase
+}
+
+// setWheel sets one wheel's rpm and power.
+func (s *simulatedRover) setWheel(wheel int, rpm, power float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	s.rpm[wheel], s.power[wheel] = rpm, power
+}
+
+// setVelocity sets the wheels to drive the rover at linear mm/sec and angular deg/sec.
+func (s *simulatedRover) setVelocity(linear, angular float64) {
+	offset := rdkutils.DegToRad(angular) * s.profile.WheelBase / 2
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	for wheel, speed := range []float64{linear - offset, linear + offset} {
+		s.rpm[wheel] = speed / s.profile.WheelCircumference * 60
+		s.power[wheel] = s.rpm[wheel] / simulatedMaxRPM
+	}
+}
+
+// setPower sets the wheels to fractions of full power, full power turning them at simulatedMaxRPM.
+func (s *simulatedRover) setPower(left, right float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	for wheel, power := range []float64{left, right} {
+		power = math.Max(-1, math.Min(1, power))
+		s.rpm[wheel], s.power[wheel] = power*simulatedMaxRPM, power
+	}
+}
+
+// state returns the wheel revolutions and pose now.
+func (s *simulatedRover) state() ([2]float64, pose) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance()
+	return s.revs, s.pose
+}
+
+// moving reports whether any wheel is turning.
+func (s *simulatedRover) moving() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rpm[leftWheel] != 0 || s.rpm[rightWheel] != 0
+}
+
+// drive holds a motion for sec seconds, or until ctx is done, then stops the wheels.
+func (s *simulatedRover) drive(ctx context.Context, sec float64) error {
+	ok := utils.SelectContextOrWait(ctx, secondsToDuration(math.Abs(sec)))
+	s.setPower(0, 0)
+	if !ok {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// simulatedBase drives both wheels of the simulated rover.
+type simulatedBase struct {
+	base.Base
+	rover *simulatedRover
+}
+
+func (b *simulatedBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	b.rover.setVelocity(linear.Y, angular.Z)
+	return nil
+}
+
+func (b *simulatedBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	b.rover.setPower(linear.Y-angular.Z, linear.Y+angular.Z)
+	return nil
+}
+
+func (b *simulatedBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	if distanceMm == 0 || mmPerSec == 0 {
+		return nil
+	}
+	distance := float64(distanceMm)
+	b.rover.setVelocity(math.Abs(mmPerSec)*sign(distance*mmPerSec), 0)
+	return b.rover.drive(ctx, distance/mmPerSec)
+}
+
+func (b *simulatedBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	if angleDeg == 0 || degsPerSec == 0 {
+		return nil
+	}
+	b.rover.setVelocity(0, math.Abs(degsPerSec)*sign(angleDeg*degsPerSec))
+	return b.rover.drive(ctx, angleDeg/degsPerSec)
+}
+
+func (b *simulatedBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	b.rover.setPower(0, 0)
+	return nil
+}
+
+func (b *simulatedBase) IsMoving(ctx context.Context) (bool, error) {
+	return b.rover.moving(), nil
+}
+
+// simulatedMotor drives one wheel of the simulated rover, its position is the wheel's encoder revolutions.
+type simulatedMotor struct {
+	motor.Motor
+	rover *simulatedRover
+	wheel int
+}
+
+func (m *simulatedMotor) SetRPM(ctx context.Context, rpm float64, extra map[string]interface{}) error {
+	m.rover.setWheel(m.wheel, rpm, rpm/simulatedMaxRPM)
+	return nil
+}
+
+func (m *simulatedMotor) SetPower(ctx context.Context, powerPct float64, extra map[string]interface{}) error {
+	m.rover.setWheel(m.wheel, powerPct*simulatedMaxRPM, powerPct)
+	return nil
+}
+
+func (m *simulatedMotor) GoFor(ctx context.Context, rpm, revolutions float64, extra map[string]interface{}) error {
+	if rpm == 0 || revolutions == 0 {
+		return nil
+	}
+	return m.goFor(ctx, math.Abs(rpm)*sign(rpm*revolutions), revolutions/rpm*60)
+}
+
+func (m *simulatedMotor) GoTo(ctx context.Context, rpm, positionRevolutions float64, extra map[string]interface{}) error {
+	pos, err := m.Position(ctx, nil)
+	if err != nil || rpm == 0 || pos == positionRevolutions {
+		return err
+	}
+	return m.goFor(ctx, math.Abs(rpm)*sign(positionRevolutions-pos), (positionRevolutions-pos)/rpm*60)
+}
+
+// goFor turns the wheel at rpm for sec seconds, then stops it.
+func (m *simulatedMotor) goFor(ctx context.Context, rpm, sec float64) error {
+	m.rover.setWheel(m.wheel, rpm, rpm/simulatedMaxRPM)
+	ok := utils.SelectContextOrWait(ctx, secondsToDuration(math.Abs(sec)))
+	m.rover.setWheel(m.wheel, 0, 0)
+	if !ok {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (m *simulatedMotor) Stop(ctx context.Context, extra map[string]interface{}) error {
+	m.rover.setWheel(m.wheel, 0, 0)
+	return nil
+}
+
+// ResetZeroPosition resets the wheel's encoder and makes the current position -offset, as the rdk's motors do.
+func (m *simulatedMotor) ResetZeroPosition(ctx context.Context, offset float64, extra map[string]interface{}) error {
+	m.rover.mu.Lock()
+	defer m.rover.mu.Unlock()
+	m.rover.advance()
+	m.rover.encoderZero[m.wheel] = m.rover.revs[m.wheel]
+	m.rover.motorOffset[m.wheel] = -offset
+	return nil
+}
+
+func (m *simulatedMotor) Position(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	m.rover.mu.Lock()
+	defer m.rover.mu.Unlock()
+	m.rover.advance()
+	return m.rover.revs[m.wheel] - m.rover.encoderZero[m.wheel] + m.rover.motorOffset[m.wheel], nil
+}
+
+func (m *simulatedMotor) IsPowered(ctx context.Context, extra map[string]interface{}) (bool, float64, error) {
+	m.rover.mu.Lock()
+	defer m.rover.mu.Unlock()
+	return m.rover.power[m.wheel] != 0, m.rover.power[m.wheel], nil
+}
+
+func (m *simulatedMotor) IsMoving(ctx context.Context) (bool, error) {
+	m.rover.mu.Lock()
+	defer m.rover.mu.Unlock()
+	return m.rover.rpm[m.wheel] != 0, nil
+}
+
+// simulatedEncoder counts the ticks of one wheel of the simulated rover.
+type simulatedEncoder struct {
+	encoder.Encoder
+	rover *simulatedRover
+	wheel int
+}
+
+func (e *simulatedEncoder) Position(ctx context.Context, positionType encoder.PositionType, extra map[string]interface{}) (float64, encoder.PositionType, error) {
+	e.rover.mu.Lock()
+	defer e.rover.mu.Unlock()
+	e.rover.advance()
+	return (e.rover.revs[e.wheel] - e.rover.encoderZero[e.wheel]) * e.rover.profile.TicksPerRotation, encoder.PositionTypeTicks, nil
+}
+
+func (e *simulatedEncoder) ResetPosition(ctx context.Context, extra map[string]interface{}) error {
+	e.rover.mu.Lock()
+	defer e.rover.mu.Unlock()
+	e.rover.advance()
+	e.rover.encoderZero[e.wheel] = e.rover.revs[e.wheel]
+	return nil
+}
+
+// simulatedOdometry reports the simulated rover's pose and velocity as wheeled odometry does, with positions
+// relative to where it was last reset. Its other readings are the fake movement sensor's.
+type simulatedOdometry struct {
+	movementsensor.MovementSensor
+	rover *simulatedRover
+}
+
+func (o *simulatedOdometry) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := cmd["reset"]; !ok {
+		return o.MovementSensor.DoCommand(ctx, cmd)
+	}
+	o.rover.mu.Lock()
+	defer o.rover.mu.Unlock()
+	o.rover.advance()
+	o.rover.pose = pose{}
+	return map[string]interface{}{}, nil
+}
+
+func (o *simulatedOdometry) Position(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+	_, p := o.rover.state()
+	// relative positions are meters forward and to the right
+	return geo.NewPoint(p.point.X/1000, -p.point.Y/1000), 0, nil
+}
+
+func (o *simulatedOdometry) Orientation(ctx context.Context, extra map[string]interface{}) (spatialmath.Orientation, error) {
+	_, p := o.rover.state()
+	return &spatialmath.OrientationVector{OZ: 1, Theta: p.theta}, nil
+}
+
+func (o *simulatedOdometry) LinearVelocity(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	o.rover.mu.Lock()
+	defer o.rover.mu.Unlock()
+	linear, _ := o.rover.velocity()
+	return r3.Vector{Y: linear / 1000}, nil
+}
+
+func (o *simulatedOdometry) AngularVelocity(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+	o.rover.mu.Lock()
+	defer o.rover.mu.Unlock()
+	_, angular := o.rover.velocity()
+	return spatialmath.AngularVelocity{Z: rdkutils.RadToDeg(angular)}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !simulate
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/robot"
+)
+
+// newSimulatedRobot is only built with the simulate tag, which keeps the in-process robot and the fake
+// components out of the binary that runs on the rover.
+func newSimulatedRobot(ctx context.Context, logger logging.Logger, names componentNames) (robot.LocalRobot, error) {
+	return nil, errors.New("this canary was built without simulation, build it with -tags simulate to use --simulate")
+}
@@ -0,0 +1,79 @@
+//go:build simulate
+
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+
+	"rovercanary/samples"
+)
+
+func TestSimulatedRover(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	machine, err := newSimulatedRobot(ctx, logger, simulatedComponents)
+	test.That(t, err, test.ShouldBeNil)
+	defer machine.Close(ctx)
+
+	b, err := base.FromRobot(machine, simulatedComponents.WheeledBase)
+	test.That(t, err, test.ShouldBeNil)
+	odometry, err := movementsensor.FromRobot(machine, simulatedComponents.Odometry)
+	test.That(t, err, test.ShouldBeNil)
+	left, err := motor.FromRobot(machine, simulatedComponents.LeftMotor)
+	test.That(t, err, test.ShouldBeNil)
+	r := newRunner(logger, machine, canaryConfig{Components: simulatedComponents}, testPlan{}, simulatedProfile, t.TempDir())
+	r.encoders.left, err = encoder.FromRobot(machine, simulatedComponents.LeftEncoder)
+	test.That(t, err, test.ShouldBeNil)
+	r.encoders.right, err = encoder.FromRobot(machine, simulatedComponents.RightEncoder)
+	test.That(t, err, test.ShouldBeNil)
+
+	// the simulated rover moves as it is told to, so every test passes
+	for _, tc := range []struct {
+		name string
+		run  func(res *testResult, des, data *samples.Writer) error
+	}{
+		{"set_velocity", func(res *testResult, des, data *samples.Writer) error {
+			return r.setVelocityTest(ctx, b, odometry, r3.Vector{Y: 100}, r3.Vector{Z: 30}, testTolerance(), res, des, data)
+		}},
+		{"move_straight", func(res *testResult, des, data *samples.Writer) error {
+			return r.moveStraightTest(ctx, b, odometry, -200, 400, testTolerance(), res, des, data)
+		}},
+		{"spin", func(res *testResult, des, data *samples.Writer) error {
+			return r.spinTest(ctx, b, odometry, 40, 80, true, testTolerance(), res, des, data)
+		}},
+		{"base_set_power", func(res *testResult, des, data *samples.Writer) error {
+			return r.baseSetPowerTest(ctx, b, odometry, 0.5, testTolerance(), res)
+		}},
+		{"go_for", func(res *testResult, des, data *samples.Writer) error {
+			return r.goForTest(ctx, left, odometry, 60, 0.5, testTolerance(), res, des, data)
+		}},
+		{"go_to", func(res *testResult, des, data *samples.Writer) error {
+			return r.goToTest(ctx, left, odometry, 60, 0.5, testTolerance(), res, des, data)
+		}},
+		{"set_rpm", func(res *testResult, des, data *samples.Writer) error {
+			return r.setRPMTest(ctx, left, odometry, 60, testTolerance(), res, des, data)
+		}},
+		{"motor_set_power", func(res *testResult, des, data *samples.Writer) error {
+			return r.motorSetPowerTest(ctx, left, 0.5, testTolerance(), res)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			des, data := newSampleWriter(t, io.Discard, samples.Desired), newSampleWriter(t, io.Discard, samples.Measured)
+			res := newResult("test", "simulated", tc.name, nil)
+			err := tc.run(res, des, data)
+			res.finish(err)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, res.Status, test.ShouldEqual, statusPass)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"rovercanary/samples"
+)
+
+// step response bounds, as a fraction of the step from the previous goal to the new one
+const (
+	riseFrom = 0.1
+	riseTo   = 0.9
+)
+
+// default step response thresholds, times default to the step's settle time
+const (
+	defaultOvershoot    = 0.5
+	defaultSettlingBand = 0.2
+)
+
+// stepThresholds are the limits a step response is judged against. Errors and overshoot are fractions of the
+// step size, times are seconds from the command.
+type stepThresholds struct {
+	RiseSec          float64 `json:"rise_sec" yaml:"rise_sec"`
+	Overshoot        float64 `json:"overshoot" yaml:"overshoot"`
+	SettlingSec      float64 `json:"settling_sec" yaml:"settling_sec"`
+	SettlingBand     float64 `json:"settling_band" yaml:"settling_band"` // band around the goal the response settles in
+	SteadyStateError float64 `json:"steady_state_error" yaml:"steady_state_error"`
+	RMSError         float64 `json:"rms_error" yaml:"rms_error"`
+}
+
+// withDefaults fills unset thresholds from the step's settle time and speed tolerance.
+func (t stepThresholds) withDefaults(settle time.Duration, speed float64) stepThresholds {
+	if t.RiseSec == 0 {
+		t.RiseSec = settle.Seconds()
+	}
+	if t.SettlingSec == 0 {
+		t.SettlingSec = settle.Seconds()
+	}
+	if t.Overshoot == 0 {
+		t.Overshoot = defaultOvershoot
+	}
+	if t.SettlingBand == 0 {
+		t.SettlingBand = defaultSettlingBand
+	}
+	if t.SteadyStateError == 0 {
+		t.SteadyStateError = speed
+	}
+	if t.RMSError == 0 {
+		t.RMSError = speed
+	}
+	return t
+}
+
+func (t stepThresholds) negative() bool {
+	return t.RiseSec < 0 || t.Overshoot < 0 || t.SettlingSec < 0 || t.SettlingBand < 0 || t.SteadyStateError < 0 || t.RMSError < 0
+}
+
+// stepMetrics describe how a velocity or rpm responded to a step from one goal to another.
+type stepMetrics struct {
+	// rise is the time from reaching riseFrom to riseTo of the step, reached is false when it never got there
+	rise    time.Duration
+	reached bool
+	// overshoot is how far past the goal the response went, as a fraction of the step
+	overshoot float64
+	// settling is the time from the command until the response stayed within the settling band
+	settling time.Duration
+	settled  bool
+	// steadyStateErr is the mean error while sampling at steady state and rmsErr its root mean square,
+	// both in the units of the goal
+	steadyStateErr float64
+	rmsErr         float64
+	steadySamples  int
+}
+
+// stepResponse is the part of a recorded response analyzed for one step: the samples from the command until
+// steady-state sampling ended, and when steady-state sampling started.
+type stepResponse struct {
+	commandMs int64
+	steadyMs  int64
+	endMs     int64
+	from      float64
+	goal      float64
+	value     func(samples.Record) float64
+}
+
+// analyze computes the step's metrics from the measured records of its test case.
+func (s stepResponse) analyze(data []samples.Record, band float64) stepMetrics {
+	var m stepMetrics
+	step := s.goal - s.from
+	// progress is 0 at the previous goal and 1 at the new one
+	progress := func(v float64) float64 { return (v - s.from) / step }
+
+	var riseStart time.Duration
+	started, out := false, true
+	peak := 0.0
+	var sum, sumSq float64
+	for _, rec := range data {
+		if rec.Marker != "" || rec.TimeMs < s.commandMs || rec.TimeMs > s.endMs {
+			continue
+		}
+		t := time.Duration(rec.TimeMs-s.commandMs) * time.Millisecond
+		v := s.value(rec)
+		p := progress(v)
+
+		if !started && p >= riseFrom {
+			riseStart, started = t, true
+		}
+		if started && !m.reached && p >= riseTo {
+			m.rise, m.reached = t-riseStart, true
+		}
+		peak = math.Max(peak, p)
+		if math.Abs(p-1) > band {
+			out = true
+		} else if out {
+			m.settling, out = t, false
+		}
+
+		if rec.TimeMs >= s.steadyMs {
+			e := v - s.goal
+			sum += e
+			sumSq += e * e
+			m.steadySamples++
+		}
+	}
+	m.settled = !out
+	m.overshoot = math.Max(0, peak-1)
+	if m.steadySamples != 0 {
+		m.steadyStateErr = sum / float64(m.steadySamples)
+		m.rmsErr = math.Sqrt(sumSq / float64(m.steadySamples))
+	}
+	return m
+}
+
+// checkStep checks a step's metrics against its thresholds. name names the value that stepped, and scale is
+// the size of the step the fractional thresholds apply to.
+func checkStep(res *testResult, name string, m stepMetrics, scale float64, t stepThresholds) error {
+	var failed []string
+	if m.reached {
+		if !res.checkMax(name+" rise time (sec)", m.rise.Seconds(), t.RiseSec) {
+			failed = append(failed, "rise time")
+		}
+	} else {
+		res.missing(name+" rise time (sec)", t.RiseSec)
+		failed = append(failed, "rise time")
+	}
+	if !res.checkMax(name+" overshoot", m.overshoot, t.Overshoot) {
+		failed = append(failed, "overshoot")
+	}
+	if m.settled {
+		if !res.checkMax(name+" settling time (sec)", m.settling.Seconds(), t.SettlingSec) {
+			failed = append(failed, "settling time")
+		}
+	} else {
+		res.missing(name+" settling time (sec)", t.SettlingSec)
+		failed = append(failed, "settling time")
+	}
+	if m.steadySamples == 0 {
+		res.missing(name+" steady-state error", t.SteadyStateError*scale)
+		res.missing(name+" rms error", t.RMSError*scale)
+		failed = append(failed, "steady-state error", "rms error")
+	} else {
+		if !res.checkMax(name+" steady-state error", math.Abs(m.steadyStateErr), t.SteadyStateError*scale) {
+			failed = append(failed, "steady-state error")
+		}
+		if !res.checkMax(name+" rms error", m.rmsErr, t.RMSError*scale) {
+			failed = append(failed, "rms error")
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("%v step response out of tolerance: %v", name, failed)
+	}
+	return nil
+}
+
+// stepField is a value a step response is analyzed for.
+type stepField struct {
+	name  string
+	value func(samples.Record) float64
+}
+
+var (
+	velocityFields = []stepField{
+		{"linear velocity", func(r samples.Record) float64 { return r.LinearVelocity }},
+		{"angular velocity", func(r samples.Record) float64 { return r.AngularVelocity }},
+	}
+	rpmFields = []stepField{
+		{"rpm", func(r samples.Record) float64 { return r.RPM }},
+	}
+)
+
+// recordedStep is one step of a test case's desired records: the start marker written when the goal was
+// commanded and the goal records written when steady-state sampling started and ended.
+type recordedStep struct {
+	command samples.Record
+	steady  samples.Record
+	end     samples.Record
+}
+
+// recordedSteps returns the steps in a case's desired records. Recordings made before start markers were
+// written have none.
+func recordedSteps(des []samples.Record) []recordedStep {
+	var steps []recordedStep
+	for i := 0; i+2 < len(des); i++ {
+		if des[i].Marker == samples.Start && des[i+1].Marker == "" && des[i+2].Marker == "" {
+			steps = append(steps, recordedStep{des[i], des[i+1], des[i+2]})
+		}
+	}
+	return steps
+}
+
+// checkSteps checks the step response of every field for every step recorded for a test case, each step
+// starting from the previous step's goal and the first from rest. prefixes name the steps, in order.
+func checkSteps(res *testResult, des, data []samples.Record, fields []stepField, tol tolerance, prefixes ...string) error {
+	var firstErr error
+	from := samples.Record{}
+	for i, step := range recordedSteps(des) {
+		prefix := ""
+		if i < len(prefixes) {
+			prefix = prefixes[i]
+		}
+		for _, f := range fields {
+			s := stepResponse{
+				commandMs: step.command.TimeMs,
+				steadyMs:  step.steady.TimeMs,
+				endMs:     step.end.TimeMs,
+				from:      f.value(from),
+				goal:      f.value(step.command),
+				value:     f.value,
+			}
+			if err := checkStepResponse(res, prefix+f.name, data, s, tol); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		from = step.command
+	}
+	return firstErr
+}
+
+// response collects the records a test writes so its step response can be analyzed.
+type response struct {
+	des  []samples.Record
+	data []samples.Record
+}
+
+// recordResponse collects the records written to des and data until the returned function is called.
+func recordResponse(des, data *samples.Writer) (*response, func()) {
+	resp := &response{}
+	stopDes := des.Observe(func(rec samples.Record) { resp.des = append(resp.des, rec) })
+	stopData := data.Observe(func(rec samples.Record) { resp.data = append(resp.data, rec) })
+	return resp, func() {
+		stopDes()
+		stopData()
+	}
+}
+
+// checkStepResponse analyzes and checks one step of a recorded response. Nothing is checked when the value
+// did not step.
+func checkStepResponse(res *testResult, name string, data []samples.Record, s stepResponse, tol tolerance) error {
+	scale := math.Abs(s.goal - s.from)
+	if scale == 0 {
+		return nil
+	}
+	t := tol.step.withDefaults(tol.settle, tol.speed)
+	return checkStep(res, name, s.analyze(data, t.SettlingBand), scale, t)
+}
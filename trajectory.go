@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+
+	rdkutils "go.viam.com/rdk/utils"
+
+	"rovercanary/plots"
+)
+
+// gridLeg is one straight or arc of the grid: the poses it was planned from and to, and the poses of the base
+// when it started and ended it and while it drove it. Arcs also keep their radius (mm) and angle (deg).
+type gridLeg struct {
+	from, to      pose
+	radius, angle float64
+	start, end    pose
+	samples       []pose
+}
+
+// length is the planned distance of the leg in mm.
+func (leg gridLeg) length() float64 {
+	if leg.radius == 0 {
+		return leg.from.distanceTo(leg.to)
+	}
+	return leg.radius * math.Abs(rdkutils.DegToRad(leg.angle))
+}
+
+// mid is the planned pose halfway along the leg.
+func (leg gridLeg) mid() pose {
+	if leg.radius == 0 {
+		return leg.from.moved(leg.from.progressTo(leg.to) / 2)
+	}
+	return leg.from.arced(leg.radius, leg.angle/2)
+}
+
+// crossTrack is how far a pose is from the leg's planned line or circle in mm.
+func (leg gridLeg) crossTrack(p pose) float64 {
+	if leg.radius == 0 {
+		return math.Abs(leg.from.crossTrackTo(p))
+	}
+	return math.Abs(p.point.Distance(leg.from.arcCenter(leg.radius, leg.angle)) - leg.radius)
+}
+
+// driven is how far the base drove the leg in mm: along the planned direction from where it started a straight,
+// which is backwards for a negative distance, or around the planned center of an arc, sampled so an arc of a full turn or more is measured as it happened.
+func (leg gridLeg) driven() float64 {
+	if leg.radius == 0 {
+		start := pose{point: leg.start.point, theta: leg.from.theta}
+		return start.progressTo(leg.end) * sign(leg.from.progressTo(leg.to))
+	}
+	center := leg.from.arcCenter(leg.radius, leg.angle)
+	var around headingTracker
+	for _, p := range append(append([]pose{leg.start}, leg.samples...), leg.end) {
+		d := p.point.Sub(center)
+		around.add(math.Atan2(d.Y, d.X))
+	}
+	return rdkutils.DegToRad(around.turned()) * sign(leg.angle) * leg.radius
+}
+
+// gridTurn is one spin of the grid: the heading (rad) planned after it and the pose the base ended it at.
+type gridTurn struct {
+	planned float64
+	end     pose
+}
+
+// analyzeGrid computes the trajectory metrics of a grid run from its legs and turns, and the pose it ended at
+// against the one it was planned to.
+func analyzeGrid(legs []gridLeg, turns []gridTurn, end, goal pose) plots.GridMetrics {
+	m := plots.GridMetrics{End: gridPoint(end), Goal: gridPoint(goal), ClosureMm: end.distanceTo(goal)}
+	for _, leg := range legs {
+		seg := plots.GridSegment{From: gridPoint(leg.from), Mid: gridPoint(leg.mid()), To: gridPoint(leg.to), LengthMm: leg.length()}
+
+		// cross-track error is measured from the planned line or arc, at every sample and where the leg ended
+		sumSq := 0.0
+		driven := append(append([]pose{}, leg.samples...), leg.end)
+		for _, p := range driven {
+			xte := leg.crossTrack(p)
+			seg.MaxCrossTrackMm = math.Max(seg.MaxCrossTrackMm, xte)
+			sumSq += xte * xte
+		}
+		seg.RMSCrossTrackMm = math.Sqrt(sumSq / float64(len(driven)))
+
+		seg.DistanceErrorMm = leg.driven() - leg.length()
+		m.Segments = append(m.Segments, seg)
+	}
+	for _, turn := range turns {
+		m.Spins = append(m.Spins, plots.GridSpin{
+			At:              gridPoint(turn.end),
+			HeadingErrorDeg: rdkutils.RadToDeg(wrapAngle(turn.end.theta - turn.planned)),
+		})
+	}
+	return m
+}
+
+func gridPoint(p pose) plots.Point {
+	x, y, _ := p.record()
+	return plots.Point{X: x, Y: y}
+}
+
+// writeGridMetrics writes the grid's trajectory metrics to the run directory for the grid plot.
+func (r *Runner) writeGridMetrics(m plots.GridMetrics) {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		r.logger.Errorf("error encoding grid metrics, err = %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(r.outDir, plots.GridMetricsFile), raw, 0o644); err != nil {
+		r.logger.Errorf("error writing grid metrics, err = %v", err)
+	}
+}
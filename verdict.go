@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"rovercanary/plots"
+)
+
+// The check functions below turn estimates into pass/fail measurements on a result. Live tests and replay
+// share them, so a recorded run is judged exactly as it would be if it ran today. Each returns an error
+// describing the first measurement out of tolerance.
+
+// checkVelocity checks linear (mm/sec) and angular (deg/sec) velocity estimates. prefix names which of
+// several velocities in one test is checked.
+func checkVelocity(res *testResult, prefix string, linEst, angEst, linear, angular float64, tol tolerance) error {
+	linearErr := defaultLinearMargin
+	if linear != 0.0 {
+		linearErr = math.Abs(linear) * tol.speed
+	}
+	angularErr := defaultAngularMargin
+	if angular != 0.0 {
+		angularErr = math.Abs(angular) * tol.speed
+	}
+
+	// verify average speed is approximately requested speed
+	linearOK := res.check(prefix+"linear velocity", linEst, linear, linearErr)
+	angularOK := res.check(prefix+"angular velocity", angEst, angular, angularErr)
+	if !linearOK || !angularOK {
+		return fmt.Errorf("measured velocity (linear: %v, angular: %v) did not equal requested velocity (linear: %v, angular: %v)", linEst, angEst, linear, angular)
+	}
+	return nil
+}
+
+// checkMoveStraight checks the distance (mm) and speed (mm/sec) measured during a MoveStraight.
+func checkMoveStraight(res *testResult, measuredDist, speedEst, distance, speed float64, tol tolerance) error {
+	dir := sign(distance * speed)
+
+	// verify distance is approximately requested distance
+	if !res.check("distance", measuredDist, math.Abs(distance)*dir, math.Abs(distance)*tol.distance) {
+		return fmt.Errorf("measured distance %v did not equal requested distance %v", measuredDist, math.Abs(distance)*dir)
+	}
+
+	// verify speed is approximately requested speed
+	if !res.check("speed", speedEst, math.Abs(speed)*dir, math.Abs(speed)*tol.speed) {
+		return fmt.Errorf("measured speed %v did not equal requested speed %v", speedEst, math.Abs(speed)*dir)
+	}
+	return nil
+}
+
+// checkSpin checks the angle (deg) turned by a Spin and either its speed (deg/sec) or how long the call took.
+func checkSpin(res *testResult, measuredDist, speedEst float64, took time.Duration, distance, speed float64, testSpeed bool, tol tolerance) error {
+	dir := sign(distance * speed)
+
+	// verify distance is approximately requested distance
+	if !res.check("distance", measuredDist, math.Abs(distance)*dir, math.Abs(distance)*tol.distance) {
+		return fmt.Errorf("measured distance %v did not equal requested distance %v", measuredDist, math.Abs(distance)*dir)
+	}
+
+	if testSpeed {
+		// verify speed is approximately requested speed
+		if !res.check("speed", speedEst, math.Abs(speed)*dir, math.Abs(speed)*tol.speed) {
+			return fmt.Errorf("measured speed %v did not equal requested speed %v", speedEst, math.Abs(speed)*dir)
+		}
+	} else if took.Seconds() > 5 {
+		return fmt.Errorf("spin call took longer than expected, actual time = %v, max allowed time = %v", took.Seconds(), math.Abs(distance/speed*5))
+	}
+	return nil
+}
+
+// checkGoFor checks the revolutions turned and rpm measured during a GoFor.
+func checkGoFor(res *testResult, measuredRevs, rpmEst, rpm, revolutions float64, tol tolerance) error {
+	dir := sign(rpm * revolutions)
+
+	// verify distance is approximately requested distance
+	if !res.check("revolutions", measuredRevs, math.Abs(revolutions)*dir, math.Abs(revolutions)*tol.distance) {
+		return fmt.Errorf("measured revolutions %v did not equal requested revolutions %v", measuredRevs, revolutions)
+	}
+
+	// verify speed is approximately requested speed
+	if !res.check("rpm", rpmEst, math.Abs(rpm)*dir, math.Abs(rpm)*tol.speed) {
+		return fmt.Errorf("measured speed %v did not equal requested speed %v", rpmEst, math.Abs(rpm)*dir)
+	}
+	return nil
+}
+
+// checkGoTo checks where a GoTo started and ended, in revolutions, and the rpm measured on the way.
+func checkGoTo(res *testResult, startPos, endPos, rpmEst, rpm, position float64, tol tolerance) error {
+	// verify start position is offset by ResetZeroPosition
+	if startPos != -goToZeroOffset {
+		return fmt.Errorf("startPos = %v when it should be %v", startPos, -goToZeroOffset)
+	}
+
+	// verify end position is approximately requested end position
+	if !res.check("end position", endPos, position, tol.distance) {
+		return fmt.Errorf("measured end position %v did not equal requested end position %v", endPos, position)
+	}
+
+	// verify speed is approximately requested speed
+	if !res.check("rpm", math.Abs(rpmEst), math.Abs(rpm), math.Abs(rpm)*tol.speed) {
+		return fmt.Errorf("measured speed %v did not equal requested speed %v", math.Abs(rpmEst), math.Abs(rpm))
+	}
+	return nil
+}
+
+// checkRPM checks an rpm estimate against the rpm set on the motor. prefix names which of several rpms in one
+// test is checked.
+func checkRPM(res *testResult, prefix string, rpmEst, rpm float64, tol tolerance) error {
+	// verify speed is approximately requested speed
+	if !res.check(prefix+"rpm", rpmEst, rpm, math.Abs(rpm)*tol.speed) {
+		return fmt.Errorf("measured speed %v did not equal requested speed %v", rpmEst, rpm)
+	}
+	return nil
+}
+
+// checkGrid checks the grid's trajectory metrics: the cross-track and distance errors of every segment, the
+// heading error after every spin and the closure error.
+func checkGrid(res *testResult, m plots.GridMetrics) error {
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for i, seg := range m.Segments {
+		name := fmt.Sprintf("segment %d ", i+1)
+		if !res.checkMax(name+"max cross-track error (mm)", seg.MaxCrossTrackMm, gridCrossTrackTolerance) {
+			fail(fmt.Errorf("segment %d strayed %v mm from its planned path", i+1, seg.MaxCrossTrackMm))
+		}
+		if !res.check(name+"distance error (mm)", seg.DistanceErrorMm, 0, seg.LengthMm*gridDistanceTolerance) {
+			fail(fmt.Errorf("segment %d drove %v mm more than the planned %v mm", i+1, seg.DistanceErrorMm, seg.LengthMm))
+		}
+	}
+	for i, spin := range m.Spins {
+		if !res.check(fmt.Sprintf("spin %d heading error (deg)", i+1), spin.HeadingErrorDeg, 0, gridHeadingTolerance) {
+			fail(fmt.Errorf("heading after spin %d was %v deg off", i+1, spin.HeadingErrorDeg))
+		}
+	}
+	if !res.checkMax("closure error (mm)", m.ClosureMm, gridClosureTolerance) {
+		fail(fmt.Errorf("base ended %v mm from where it should have", m.ClosureMm))
+	}
+	return firstErr
+}
+
+// checkGridRun checks the grid's trajectory metrics and the rms error of the poses the base ended its straights
+// and arcs at against the desired ones, both starting with the pose the grid started at.
+func checkGridRun(res *testResult, m plots.GridMetrics, poses, desPoses []pose) error {
+	rmsErrorSum := 0.0
+	for i := range poses {
+		rmsErrorSum += math.Pow(poses[i].distanceTo(desPoses[i]), 2)
+	}
+	rmsErr := math.Sqrt(rmsErrorSum / float64(len(desPoses)))
+
+	rmsOK := res.checkMax("rms error (mm)", rmsErr, gridRMSTolerance)
+	if err := checkGrid(res, m); err != nil {
+		return err
+	}
+	if !rmsOK {
+		return fmt.Errorf("rms error %v mm is higher than the maximum allowed error %v mm", rmsErr, gridRMSTolerance)
+	}
+	return nil
+}
+
+// checkOdometry checks the largest divergences of the odometry from the encoders against the floors plus a
+// fraction of the largest motion the encoders measured.
+func checkOdometry(res *testResult, distance, heading, encDistance, encHeading float64, checkHeading bool) error {
+	var err error
+	distanceTol := odometryDistanceFloor + odometryDivergence*encDistance
+	if !res.checkMax("odometry distance divergence from encoders (mm)", distance, distanceTol) {
+		err = fmt.Errorf("odometry distance diverged %v mm from the encoders, more than the allowed %v mm", distance, distanceTol)
+	}
+	if !checkHeading {
+		return err
+	}
+	headingTol := odometryHeadingFloor + odometryDivergence*encHeading
+	if !res.checkMax("odometry heading divergence from encoders (deg)", heading, headingTol) && err == nil {
+		err = fmt.Errorf("odometry heading diverged %v deg from the encoders, more than the allowed %v deg", heading, headingTol)
+	}
+	return err
+}